@@ -2,23 +2,43 @@ package cmd
 
 import (
 	"github.com/JamesBalazs/lode/internal/lode"
+	"github.com/JamesBalazs/lode/internal/redact"
+	"github.com/JamesBalazs/lode/internal/report"
 	"github.com/spf13/cobra"
 )
 
 var inFormat string
+var replayRedaction = redact.Rules{}
+var replayGroupBy string
+var replayFilters []string
+var replayConfidenceIntervals bool
 
 // replayCmd represents the replay command
 var replayCmd = &cobra.Command{
 	Use:   "replay",
 	Short: "Replay a log file that was written with --out",
-	Long: `Load a log file and display the timings, response bodies, headers etc. in interactive form
+	Long: `Load a log file and display the timings, response bodies, headers etc. in interactive form.
+The file format (json or yaml) and version are detected automatically.
+Secrets in headers and bodies are redacted before they are displayed.
 
-e.g. lode replay --inFormat yaml ./out.yaml`,
+e.g. lode replay ./out.yaml`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		runData := lode.RunDataFromFile(args[0], inFormat)
-		report := runData.ToInteractiveTestReport()
-		lode.RunReport(report)
+		redactor, err := redact.New(replayRedaction)
+		cobra.CheckErr(err)
+
+		runData, err := lode.FilterRunData(lode.RunDataFromFile(args[0]), replayFilters)
+		cobra.CheckErr(err)
+		if replayGroupBy != "" {
+			_, err = report.NewGrouper(replayGroupBy)
+			cobra.CheckErr(err)
+			runData.Params.GroupBy = replayGroupBy
+		}
+		if replayConfidenceIntervals {
+			runData.Params.ConfidenceIntervals = true
+		}
+		redactor.ResponseTimings(runData.ResponseTimings)
+		lode.RunReport(lode.TestReportFromRunData(runData))
 	},
 }
 
@@ -26,4 +46,14 @@ func init() {
 	rootCmd.AddCommand(replayCmd)
 
 	replayCmd.Flags().StringVar(&inFormat, "inFormat", "json", "Format of requests in file - valid options are json and yaml")
+	cobra.CheckErr(replayCmd.Flags().MarkDeprecated("inFormat", "the format is now detected from the file contents"))
+
+	replayCmd.Flags().StringVar(&replayGroupBy, "group-by", "", "Break statuses and latency down by header:<name>, path or status class - defaults to the groupby the run was made with")
+	replayCmd.Flags().StringArrayVar(&replayFilters, "filter", []string{}, "Only replay responses in a group, in the form groupby=value, e.g. header:X-Served-By=web-2 or status=5xx - repeat the flag to match every filter")
+	replayCmd.Flags().BoolVar(&replayConfidenceIntervals, "confidenceIntervals", false, "Show a 95% confidence interval for each percentile and the mean, even if the run didn't")
+
+	replayCmd.Flags().StringSliceVar(&replayRedaction.Headers, "redactHeader", []string{}, "Header names to redact, in addition to the defaults - separate names with commas, or repeat the flag")
+	replayCmd.Flags().StringSliceVar(&replayRedaction.Fields, "redactField", []string{}, "JSONPath fields to redact from response bodies, e.g. $.token or $..password - separate paths with commas, or repeat the flag")
+	replayCmd.Flags().StringArrayVar(&replayRedaction.Patterns, "redactPattern", []string{}, "Regular expression to redact from response bodies and header values - repeat the flag to add multiple patterns")
+	replayCmd.Flags().BoolVar(&replayRedaction.NoDefaults, "no-default-redaction", false, "Don't redact the Authorization, Cookie, Set-Cookie and API-key style headers by default")
 }
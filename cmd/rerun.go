@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"github.com/JamesBalazs/lode/internal/lode"
+	"github.com/spf13/cobra"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var rerunParams = lode.Params{}
+
+// rerunCmd represents the rerun command
+var rerunCmd = &cobra.Command{
+	Use:   "rerun [filepath]",
+	Short: "Re-run a load test from a log file that was written with --out",
+	Long: `Load a log file and run the same test again, using the params recorded in the file.
+The URL, rate and duration can be overridden with flags, and headers or a proxy
+password that were redacted in the log file can be provided again with --header
+and --proxy.
+A comparison against the original run is printed after the report.
+
+e.g. lode rerun --freq 50 ./out.json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runData := lode.RunDataFromFile(args[0])
+		original := lode.TestReportFromRunData(runData)
+		original.Interactive = false
+
+		params := lode.Params(runData.Params)
+		flags := cmd.Flags()
+		if flags.Changed("url") {
+			params.Url = rerunParams.Url
+		}
+		if flags.Changed("freq") {
+			params.Freq = rerunParams.Freq
+		}
+		if flags.Changed("delay") {
+			params.Freq = 0
+			params.Delay = rerunParams.Delay
+		}
+		if flags.Changed("maxRequests") {
+			params.MaxRequests = rerunParams.MaxRequests
+		}
+		if flags.Changed("maxTime") {
+			params.MaxTime = rerunParams.MaxTime
+		}
+		if flags.Changed("header") {
+			params.Headers = replaceHeaders(params.Headers, rerunParams.Headers)
+		}
+		if flags.Changed("proxy") {
+			params.Proxy = rerunParams.Proxy
+		}
+		params.OutFile = rerunParams.OutFile
+		params.OutFormat = rerunParams.OutFormat
+		cobra.CheckErr(lode.CheckRerunnable(runData, params))
+
+		lode := lode.New(params)
+		defer lode.ExitWithCode()
+		lode.Run()
+		lode.Report()
+		lode.Compare(original)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rerunCmd)
+
+	rerunCmd.Flags().StringVarP(&rerunParams.Url, "url", "u", "", "URL to target instead of the original URL")
+	rerunCmd.Flags().IntVarP(&rerunParams.Freq, "freq", "f", 0, "Number of requests to make per second, instead of the original rate")
+	rerunCmd.Flags().DurationVarP(&rerunParams.Delay, "delay", "d", 0, "Time to wait between requests, instead of the original rate, e.g. 200ms or 1s")
+	rerunCmd.Flags().IntVarP(&rerunParams.MaxRequests, "maxRequests", "n", 0, "Maximum number of requests to make, instead of the original limit")
+	rerunCmd.Flags().DurationVarP(&rerunParams.MaxTime, "maxTime", "l", 0*time.Second, "Length of time to make requests, instead of the original limit, e.g. 20s or 1h")
+
+	rerunCmd.Flags().StringSliceVarP(&rerunParams.Headers, "header", "H", []string{}, "Request headers to add or replace, in the form X-SomeHeader=value - separate headers with commas, or repeat the flag to add multiple headers")
+
+	rerunCmd.Flags().StringVarP(&rerunParams.Proxy, "proxy", "x", "", "Proxy URL to send requests through instead of the original proxy - use this to provide a proxy password that was redacted from the log file")
+
+	rerunCmd.Flags().StringVarP(&rerunParams.OutFile, "out", "O", "", "Filepath to write requests and timing data, if provided")
+	rerunCmd.Flags().StringVar(&rerunParams.OutFormat, "outFormat", "json", "Format to use when writing requests to file - valid options are json and yaml")
+	rerunCmd.MarkFlagsMutuallyExclusive("freq", "delay")
+}
+
+// replaceHeaders adds the overrides to headers, replacing any existing header with the same name
+func replaceHeaders(headers []string, overrides []string) (result []string) {
+	overridden := make(map[string]bool)
+	for _, override := range overrides {
+		name, _, _ := strings.Cut(override, "=")
+		overridden[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, header := range headers {
+		name, _, _ := strings.Cut(header, "=")
+		if !overridden[http.CanonicalHeaderKey(name)] {
+			result = append(result, header)
+		}
+	}
+	return append(result, overrides...)
+}
@@ -0,0 +1,68 @@
+package files
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// CompressionFromPath picks a compression format from the file extension, e.g. out.json.gz.
+// Returns an empty string for uncompressed files.
+func CompressionFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".gzip":
+		return "gzip"
+	case ".zst", ".zstd":
+		return "zstd"
+	default:
+		return ""
+	}
+}
+
+// NewCompressedWriter wraps writer to compress output based on the extension of path.
+// Closing the returned writer flushes the compressed stream, but does not close writer.
+func NewCompressedWriter(writer io.Writer, path string) (io.WriteCloser, error) {
+	switch CompressionFromPath(path) {
+	case "gzip":
+		return gzip.NewWriter(writer), nil
+	case "zstd":
+		return zstd.NewWriter(writer)
+	default:
+		return nopWriteCloser{writer}, nil
+	}
+}
+
+// Decompress detects gzip or zstd data from its magic bytes and decompresses it.
+// Uncompressed data is returned unchanged.
+func Decompress(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	case bytes.HasPrefix(data, zstdMagic):
+		decoder, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		return decoder.DecodeAll(data, nil)
+	default:
+		return data, nil
+	}
+}
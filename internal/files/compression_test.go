@@ -0,0 +1,43 @@
+package files
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompressionFromPath(t *testing.T) {
+	assert.Equal(t, "gzip", CompressionFromPath("out.json.gz"))
+	assert.Equal(t, "zstd", CompressionFromPath("out.yaml.zst"))
+	assert.Equal(t, "zstd", CompressionFromPath("OUT.ZSTD"))
+	assert.Equal(t, "", CompressionFromPath("out.json"))
+}
+
+func TestWriteFileAndDecompress(t *testing.T) {
+	data := []byte(`{"Version":"2"}`)
+
+	for _, name := range []string{"out.json", "out.json.gz", "out.json.zst"} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			path := filepath.Join(t.TempDir(), name)
+
+			err := WriteFile(path, data)
+			assert.Nil(err)
+
+			written, _ := os.ReadFile(path)
+			if CompressionFromPath(name) != "" {
+				assert.NotEqual(data, written)
+			}
+			decompressed, err := Decompress(written)
+			assert.Nil(err)
+			assert.Equal(data, decompressed)
+		})
+	}
+}
+
+func TestDecompress_InvalidGzip(t *testing.T) {
+	_, err := Decompress(append(gzipMagic, 0x00))
+
+	assert.NotNil(t, err)
+}
@@ -25,6 +25,28 @@ func ReaderFromFileOrString(file string, body string) (reader io.Reader) {
 	return
 }
 
+// WriteFile writes data to the named file, compressing it if the extension calls for it.
+func WriteFile(name string, data []byte) (err error) {
+	file, err := os.Create(name)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	writer, err := NewCompressedWriter(file, name)
+	if err != nil {
+		return
+	}
+	if _, err = writer.Write(data); err != nil {
+		return
+	}
+	return writer.Close()
+}
+
 type Decoder interface {
 	Decode(v interface{}) (err error)
 }
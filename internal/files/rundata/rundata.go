@@ -0,0 +1,49 @@
+package rundata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/files"
+	"gopkg.in/yaml.v3"
+)
+
+// DetectFormat guesses whether run file contents are json or yaml.
+// Run files written as json always start with an object.
+func DetectFormat(data []byte) string {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return "json"
+	}
+	return "yaml"
+}
+
+// Decode reads a run file of any supported version and format, upgrading it to the current schema.
+func Decode(data []byte) (runData RunDataV2, err error) {
+	var header struct {
+		Version string
+	}
+	if err = decoder(data).Decode(&header); err != nil {
+		return
+	}
+
+	switch header.Version {
+	case "1":
+		var runDataV1 RunDataV1
+		if err = decoder(data).Decode(&runDataV1); err != nil {
+			return
+		}
+		runData = runDataV1.Upgrade()
+	case VersionV2:
+		err = decoder(data).Decode(&runData)
+	default:
+		err = fmt.Errorf("unsupported run file version %q", header.Version)
+	}
+	return
+}
+
+func decoder(data []byte) files.Decoder {
+	if DetectFormat(data) == "json" {
+		return json.NewDecoder(bytes.NewReader(data))
+	}
+	return yaml.NewDecoder(bytes.NewReader(data))
+}
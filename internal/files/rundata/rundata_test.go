@@ -0,0 +1,132 @@
+package rundata
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/JamesBalazs/lode/internal/report"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var runDataV2 = RunDataV2{
+	Version:     VersionV2,
+	LodeVersion: "v1.2.3",
+	Params: Params{
+		Url:            "https://www.example.com",
+		Method:         "POST",
+		Body:           `{"example":"value"}`,
+		Freq:           10,
+		Concurrency:    2,
+		MaxRequests:    20,
+		Delay:          100 * time.Millisecond,
+		Timeout:        5 * time.Second,
+		Headers:        []string{"Content-Type=application/json"},
+		Labels:         map[string]string{"build": "123"},
+		RedactHeaders:  []string{"X-Session"},
+		RedactFields:   []string{"$.token"},
+		RedactPatterns: []string{`\d{16}`},
+		TlsCiphers:     []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+		TlsAlpn:        []string{"h2"},
+		Resolve:        []string{"www.example.com:443:127.0.0.1"},
+		Proxy:          "http://proxy:3128",
+		SourceAddrs:    []string{"10.0.0.2"},
+		Percentiles:    []float64{50, 99.9},
+		Resolution:     "us",
+	},
+	Environment:   Environment{Hostname: "runner", OS: "linux", Arch: "amd64", NumCPU: 4, GoVersion: "go1.19"},
+	StartTime:     time.Unix(100, 0).UTC(),
+	FinishTime:    time.Unix(102, 0).UTC(),
+	Duration:      2 * time.Second,
+	ResponseCount: 1,
+	RequestRate:   0.5,
+	ResponseTimings: responseTimings.ResponseTimings{
+		{
+			Response: &responseTimings.Response{
+				Status:     "200 OK",
+				StatusCode: 200,
+				Header:     responseTimings.Header{HttpHeader: http.Header{"Content-Type": {"text/plain"}}},
+				Body:       "ok",
+			},
+			Timing: &responseTimings.Timing{
+				Start: time.Unix(100, 0).UTC(),
+				Done:  time.Unix(101, 0).UTC(),
+				Connection: responseTimings.Connection{
+					RemoteAddr:    "93.184.216.34:443",
+					ResolvedAddrs: []string{"93.184.216.34"},
+					Protocol:      "HTTP/2.0",
+					TlsVersion:    "TLS 1.3",
+				},
+			},
+		},
+	},
+	Concurrencies: []report.ConcurrencySample{{Limit: 1}, {Offset: 500 * time.Millisecond, Limit: 2}},
+}
+
+func TestDetectFormat(t *testing.T) {
+	assert.Equal(t, "json", DetectFormat([]byte(`{"Version":"2"}`)))
+	assert.Equal(t, "json", DetectFormat([]byte("\n  {\"Version\":\"2\"}")))
+	assert.Equal(t, "yaml", DetectFormat([]byte("version: \"2\"\n")))
+}
+
+func TestDecode_V1Json(t *testing.T) {
+	data := []byte(`{"Version":"1","Target":"GET https://www.example.com","Concurrency":4,"Duration":2000000000,"ResponseCount":1,"RequestRate":0.5,"ResponseTimings":[{"Response":{"Status":"200 OK","StatusCode":200}}]}`)
+
+	runData, err := Decode(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, VersionV2, runData.Version)
+	assert.Equal(t, "1", runData.UpgradedFrom)
+	assert.Equal(t, Params{Url: "https://www.example.com", Method: "GET", Concurrency: 4}, runData.Params)
+	assert.Equal(t, 2*time.Second, runData.Duration)
+	assert.Equal(t, 200, runData.ResponseTimings[0].Response.StatusCode)
+	assert.Equal(t, "GET https://www.example.com", runData.Target())
+}
+
+func TestDecode_V1Yaml(t *testing.T) {
+	data := []byte(`version: "1"
+target: GET https://www.example.com
+concurrency: 4
+responsecount: 1
+`)
+
+	runData, err := Decode(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, VersionV2, runData.Version)
+	assert.Equal(t, Params{Url: "https://www.example.com", Method: "GET", Concurrency: 4}, runData.Params)
+	assert.Equal(t, 1, runData.ResponseCount)
+}
+
+func TestDecode_V2Json(t *testing.T) {
+	data, _ := json.Marshal(runDataV2)
+
+	runData, err := Decode(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, runDataV2, runData)
+}
+
+func TestDecode_V2Yaml(t *testing.T) {
+	data, _ := yaml.Marshal(runDataV2)
+
+	runData, err := Decode(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, runDataV2, runData)
+}
+
+func TestDecode_UnsupportedVersion(t *testing.T) {
+	_, err := Decode([]byte(`{"Version":"99"}`))
+
+	assert.EqualError(t, err, `unsupported run file version "99"`)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	_, err := Decode(bytes.Repeat([]byte("{"), 3))
+
+	assert.NotNil(t, err)
+}
@@ -1,2 +1,38 @@
 package rundata
 
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"strings"
+	"time"
+)
+
+type RunDataV1 struct {
+	Version         string
+	Target          string
+	Concurrency     int
+	Duration        time.Duration
+	ResponseCount   int
+	RequestRate     float64
+	ResponseTimings responseTimings.ResponseTimings
+}
+
+// Upgrade converts a v1 run file to the current schema. v1 files only recorded the
+// target and concurrency, so the remaining params and the environment are left empty.
+func (runData RunDataV1) Upgrade() RunDataV2 {
+	params := Params{Concurrency: runData.Concurrency}
+	if method, url, found := strings.Cut(runData.Target, " "); found {
+		params.Method, params.Url = method, url
+	} else {
+		params.Url = runData.Target
+	}
+
+	return RunDataV2{
+		Version:         VersionV2,
+		UpgradedFrom:    runData.Version,
+		Params:          params,
+		Duration:        runData.Duration,
+		ResponseCount:   runData.ResponseCount,
+		RequestRate:     runData.RequestRate,
+		ResponseTimings: runData.ResponseTimings,
+	}
+}
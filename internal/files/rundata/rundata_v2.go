@@ -0,0 +1,129 @@
+package rundata
+
+import (
+	"github.com/JamesBalazs/lode/internal/report"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"os"
+	"runtime"
+	"time"
+)
+
+const VersionV2 = "2"
+
+// Params are the settings of a run, which lode.Params is defined from, so the two always convert directly.
+type Params struct {
+	Url                 string
+	Method              string
+	Body                string
+	File                string
+	OutFile             string
+	OutFormat           string
+	Freq                int
+	Concurrency         int
+	MaxRequests         int
+	Delay               time.Duration
+	Timeout             time.Duration
+	MaxTime             time.Duration
+	Headers             []string
+	Labels              map[string]string
+	FailFast            bool
+	IgnoreFailures      bool
+	MaxBodySize         int64
+	CaptureEvery        int
+	CaptureFailed       bool
+	BodyHashOnly        bool
+	RedactHeaders       []string
+	RedactFields        []string
+	RedactPatterns      []string
+	NoDefaultRedaction  bool
+	DisableKeepAlives   bool
+	MaxIdleConns        int
+	MaxConnsPerHost     int
+	ForceHttp1          bool
+	ForceHttp2          bool
+	H2cPriorKnowledge   bool
+	NewConnPerRequest   bool
+	TlsCert             string
+	TlsKey              string
+	TlsCaCert           string
+	TlsInsecure         bool
+	TlsServerName       string
+	TlsMinVersion       string
+	TlsMaxVersion       string
+	TlsCiphers          []string
+	TlsAlpn             []string
+	DialTimeout         time.Duration
+	TlsTimeout          time.Duration
+	HeaderTimeout       time.Duration
+	IdleBodyTimeout     time.Duration
+	Resolve             []string
+	DnsServer           string
+	NoDnsCache          bool
+	PreferIpv4          bool
+	PreferIpv6          bool
+	Proxy               string
+	NoProxy             string
+	UnixSocket          string
+	SourceAddrs         []string
+	RedirectPolicy      string
+	MaxRedirects        *int `json:",omitempty" yaml:",omitempty"` // nil follows up to 10 redirects
+	NetworkProfile      string
+	NetworkLatency      time.Duration
+	NetworkJitter       time.Duration
+	DownloadKbps        int
+	UploadKbps          int
+	DropRate            float64
+	Percentiles         []float64
+	Resolution          string
+	Heatmap             bool
+	GroupBy             string
+	Warmup              time.Duration
+	WarmupRequests      int
+	Apdex               time.Duration
+	MinApdex            float64
+	Slo                 string
+	FailOnSlo           bool
+	ConfidenceIntervals bool
+	ConvergePercentile  float64
+	ConvergeWidth       time.Duration
+	Adaptive            bool
+	AdaptiveLatency     time.Duration
+}
+
+type Environment struct {
+	Hostname  string
+	OS        string
+	Arch      string
+	NumCPU    int
+	GoVersion string
+}
+
+func CurrentEnvironment() Environment {
+	hostname, _ := os.Hostname()
+	return Environment{
+		Hostname:  hostname,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+		GoVersion: runtime.Version(),
+	}
+}
+
+type RunDataV2 struct {
+	Version         string
+	UpgradedFrom    string `json:",omitempty" yaml:",omitempty"` // the version of the file it was upgraded from, if any
+	LodeVersion     string
+	Params          Params
+	Environment     Environment
+	StartTime       time.Time
+	FinishTime      time.Time
+	Duration        time.Duration
+	ResponseCount   int
+	RequestRate     float64
+	ResponseTimings responseTimings.ResponseTimings
+	Concurrencies   []report.ConcurrencySample `json:",omitempty" yaml:",omitempty"` // how an adaptive concurrency limit changed over the run
+}
+
+func (runData RunDataV2) Target() string {
+	return runData.Params.Method + " " + runData.Params.Url
+}
@@ -0,0 +1,129 @@
+package lode
+
+import (
+	"github.com/JamesBalazs/lode/internal/report"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"math"
+	"sync"
+	"time"
+)
+
+// aimdBackoff is what the limit is multiplied by when a request fails or is slower than the latency target
+const aimdBackoff = 0.5
+
+// adaptiveLimit adjusts how many requests can be in flight with AIMD, additive increase and multiplicative
+// decrease, the way a well-behaved client backs off an overloaded service. Each window of successful requests,
+// as many as the limit so about one round trip, raises it by one, or doubles it until the first slowdown.
+// A failed or slow request cuts it by aimdBackoff, once for all the requests in flight at the time.
+type adaptiveLimit struct {
+	mutex       sync.Mutex
+	changed     *sync.Cond
+	limit       int
+	max         int
+	latency     time.Duration // a slower request counts as a slowdown, if set
+	slowStart   bool
+	inFlight    int
+	busiest     int // most requests in flight during the window
+	windowCount int
+	lastCut     time.Time
+	stopped     bool
+	start       time.Time
+	history     []report.ConcurrencySample
+}
+
+func (p Params) adaptiveLimit(start time.Time) *adaptiveLimit {
+	if !p.Adaptive {
+		return nil
+	}
+	limit := &adaptiveLimit{limit: 1, max: p.Concurrency, latency: p.AdaptiveLatency, slowStart: true, start: start}
+	limit.changed = sync.NewCond(&limit.mutex)
+	limit.history = []report.ConcurrencySample{{Limit: 1}}
+	return limit
+}
+
+// acquire waits until the worker is within the limit, and returns false once the run has stopped.
+// Workers are numbered from 1, so lowering the limit parks the highest numbered workers.
+func (a *adaptiveLimit) acquire(workerId int) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for workerId > a.limit && !a.stopped {
+		a.changed.Wait()
+	}
+	return !a.stopped
+}
+
+func (a *adaptiveLimit) begin() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.inFlight++
+	if a.inFlight > a.busiest {
+		a.busiest = a.inFlight
+	}
+}
+
+// observe adjusts the limit for a finished request
+func (a *adaptiveLimit) observe(response responseTimings.ResponseTiming) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.inFlight--
+
+	slow := a.latency > 0 && response.Timing.TotalDuration() > a.latency
+	if slow || response.Response.Failed() {
+		// requests sent before the last cut were made at the old limit, so don't cut again for them
+		if response.Timing.Start.Before(a.lastCut) {
+			return
+		}
+		a.slowStart = false
+		// round down, but never below one request, so small limits are still cut rather than left in place
+		limit := int(math.Floor(float64(a.limit) * aimdBackoff))
+		if limit < 1 {
+			limit = 1
+		}
+		a.setLimit(limit)
+		a.lastCut = time.Now()
+		return
+	}
+
+	a.windowCount++
+	if a.windowCount < a.limit {
+		return
+	}
+	// only raise the limit when it was being used, otherwise the rate is holding requests back instead
+	if a.busiest*2 >= a.limit {
+		if a.slowStart {
+			a.setLimit(a.limit * 2)
+		} else {
+			a.setLimit(a.limit + 1)
+		}
+	}
+	a.windowCount, a.busiest = 0, a.inFlight
+}
+
+func (a *adaptiveLimit) setLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > a.max {
+		limit = a.max
+	}
+	a.windowCount, a.busiest = 0, a.inFlight
+	if limit == a.limit {
+		return
+	}
+	a.limit = limit
+	a.history = append(a.history, report.ConcurrencySample{Offset: time.Since(a.start), Limit: limit})
+	a.changed.Broadcast()
+}
+
+func (a *adaptiveLimit) stop() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.stopped = true
+	a.changed.Broadcast()
+}
+
+func (a *adaptiveLimit) samples() []report.ConcurrencySample {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return append([]report.ConcurrencySample{}, a.history...)
+}
@@ -0,0 +1,140 @@
+package lode
+
+import (
+	"github.com/JamesBalazs/lode/internal/report"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func adaptiveResponse(statusCode int, latency time.Duration) responseTimings.ResponseTiming {
+	start := time.Now()
+	return responseTimings.ResponseTiming{
+		Response: &responseTimings.Response{StatusCode: statusCode},
+		Timing:   &responseTimings.Timing{Start: start, GotConn: start, Done: start.Add(latency)},
+	}
+}
+
+// observeWindow finishes as many successful requests as the limit, with every one of them in flight at once
+func observeWindow(limit *adaptiveLimit) {
+	count := limit.limit
+	for i := 0; i < count; i++ {
+		limit.begin()
+	}
+	for i := 0; i < count; i++ {
+		limit.observe(adaptiveResponse(200, 10*time.Millisecond))
+	}
+}
+
+func TestParams_AdaptiveLimit(t *testing.T) {
+	assert.Nil(t, Params{Concurrency: 10}.adaptiveLimit(time.Now()))
+
+	limit := Params{Concurrency: 10, Adaptive: true, AdaptiveLatency: time.Second}.adaptiveLimit(time.Now())
+
+	assert.Equal(t, 1, limit.limit)
+	assert.Equal(t, 10, limit.max)
+	assert.Equal(t, time.Second, limit.latency)
+	assert.Equal(t, []report.ConcurrencySample{{Limit: 1}}, limit.samples())
+}
+
+func TestAdaptiveLimit_Observe(t *testing.T) {
+	assert := assert.New(t)
+	limit := Params{Concurrency: 20, Adaptive: true, AdaptiveLatency: 100 * time.Millisecond}.adaptiveLimit(time.Now())
+
+	// slow start doubles the limit each window, up to the first slowdown
+	observeWindow(limit)
+	observeWindow(limit)
+	observeWindow(limit)
+	assert.Equal(8, limit.limit)
+
+	limit.begin()
+	limit.observe(adaptiveResponse(200, 200*time.Millisecond))
+	assert.Equal(4, limit.limit)
+	assert.False(limit.slowStart)
+
+	observeWindow(limit)
+	assert.Equal(5, limit.limit)
+
+	limit.begin()
+	limit.observe(adaptiveResponse(503, 10*time.Millisecond))
+	assert.Equal(2, limit.limit)
+
+	limits := []int{}
+	for _, sample := range limit.samples() {
+		limits = append(limits, sample.Limit)
+	}
+	assert.Equal([]int{1, 2, 4, 8, 4, 5, 2}, limits)
+}
+
+func TestAdaptiveLimit_Observe_CutsOncePerRoundTrip(t *testing.T) {
+	limit := Params{Concurrency: 20, Adaptive: true}.adaptiveLimit(time.Now())
+	limit.limit = 10
+	sentBefore := adaptiveResponse(503, 10*time.Millisecond)
+
+	limit.observe(adaptiveResponse(503, 10*time.Millisecond))
+	limit.observe(sentBefore)
+
+	assert.Equal(t, 5, limit.limit)
+}
+
+func TestAdaptiveLimit_Observe_SmallLimit(t *testing.T) {
+	assert := assert.New(t)
+	limit := Params{Concurrency: 20, Adaptive: true}.adaptiveLimit(time.Now())
+	limit.limit = 4
+
+	limit.observe(adaptiveResponse(503, 10*time.Millisecond))
+	assert.Equal(2, limit.limit)
+	limit.observe(adaptiveResponse(503, 10*time.Millisecond))
+	assert.Equal(1, limit.limit)
+}
+
+func TestAdaptiveLimit_Observe_Bounds(t *testing.T) {
+	assert := assert.New(t)
+	limit := Params{Concurrency: 3, Adaptive: true}.adaptiveLimit(time.Now())
+
+	observeWindow(limit)
+	observeWindow(limit)
+	assert.Equal(3, limit.limit)
+
+	limit.observe(adaptiveResponse(503, 10*time.Millisecond))
+	assert.Equal(1, limit.limit)
+	limit.observe(adaptiveResponse(503, 10*time.Millisecond))
+	assert.Equal(1, limit.limit)
+}
+
+func TestAdaptiveLimit_Observe_UnusedLimit(t *testing.T) {
+	limit := Params{Concurrency: 20, Adaptive: true}.adaptiveLimit(time.Now())
+	limit.limit, limit.slowStart = 10, false
+
+	// one request in flight at a time, so the rate is what's holding requests back
+	for i := 0; i < 10; i++ {
+		limit.begin()
+		limit.observe(adaptiveResponse(200, 10*time.Millisecond))
+	}
+
+	assert.Equal(t, 10, limit.limit)
+}
+
+func TestAdaptiveLimit_Acquire(t *testing.T) {
+	limit := Params{Concurrency: 2, Adaptive: true}.adaptiveLimit(time.Now())
+	acquired := make(chan bool)
+
+	assert.True(t, limit.acquire(1))
+	go func() {
+		acquired <- limit.acquire(2)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("worker 2 acquired a slot above the limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	limit.mutex.Lock()
+	limit.setLimit(2)
+	limit.mutex.Unlock()
+	assert.True(t, <-acquired)
+
+	limit.stop()
+	assert.False(t, limit.acquire(3))
+}
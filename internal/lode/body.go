@@ -0,0 +1,59 @@
+package lode
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptGzip asks for a gzipped response in the same cases the transport would, so the body can be
+// decompressed here instead, where the bytes received can be counted. It returns a copy of the request to send.
+func acceptGzip(request *http.Request) (*http.Request, bool) {
+	if request.Header.Get("Accept-Encoding") != "" || request.Header.Get("Range") != "" || request.Method == http.MethodHead {
+		return request, false
+	}
+	sent := *request
+	sent.Header = request.Header.Clone()
+	sent.Header.Set("Accept-Encoding", "gzip")
+	return &sent, true
+}
+
+type countingReader struct {
+	io.Reader
+	count int64
+}
+
+func (c *countingReader) Read(p []byte) (n int, err error) {
+	n, err = c.Reader.Read(p)
+	c.count += int64(n)
+	return
+}
+
+// readBody drains the response body, only keeping it if keep is set, and counts the bytes received and
+// the bytes after decompression. Like the transport, it decompresses gzip bodies it asked for itself.
+func readBody(response *http.Response, decompress bool, keep bool) (body []byte, wireSize int64, decodedSize int64, err error) {
+	wire := &countingReader{Reader: response.Body}
+	var reader io.Reader = wire
+	if decompress && strings.EqualFold(response.Header.Get("Content-Encoding"), "gzip") {
+		gzipReader, err := gzip.NewReader(wire)
+		if err == io.EOF {
+			return nil, wire.count, 0, nil
+		} else if err != nil {
+			return nil, wire.count, 0, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+		response.Header.Del("Content-Encoding")
+		response.Header.Del("Content-Length")
+		response.Uncompressed = true
+	}
+
+	decoded := &countingReader{Reader: reader}
+	if keep {
+		body, err = io.ReadAll(decoded)
+	} else {
+		_, err = io.Copy(io.Discard, decoded)
+	}
+	return body, wire.count, decoded.count, err
+}
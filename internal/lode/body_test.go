@@ -0,0 +1,106 @@
+package lode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/JamesBalazs/lode/internal/lode/mocks"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipped(body string) []byte {
+	buffer := bytes.Buffer{}
+	writer := gzip.NewWriter(&buffer)
+	_, _ = writer.Write([]byte(body))
+	_ = writer.Close()
+	return buffer.Bytes()
+}
+
+func TestAcceptGzip(t *testing.T) {
+	assert := assert.New(t)
+	request, _ := http.NewRequest("GET", "http://lode.test", nil)
+
+	sent, decompress := acceptGzip(request)
+
+	assert.True(decompress)
+	assert.Equal("gzip", sent.Header.Get("Accept-Encoding"))
+	assert.Empty(request.Header.Get("Accept-Encoding"))
+
+	request.Header.Set("Accept-Encoding", "br")
+	_, decompress = acceptGzip(request)
+	assert.False(decompress)
+
+	request, _ = http.NewRequest("HEAD", "http://lode.test", nil)
+	_, decompress = acceptGzip(request)
+	assert.False(decompress)
+}
+
+func TestReadBody(t *testing.T) {
+	assert := assert.New(t)
+	body := strings.Repeat("lode ", 100)
+	compressed := gzipped(body)
+	newResponse := func() *http.Response {
+		return &http.Response{
+			Header: http.Header{"Content-Encoding": {"gzip"}},
+			Body:   io.NopCloser(bytes.NewReader(compressed)),
+		}
+	}
+
+	response := newResponse()
+	read, wireSize, decodedSize, err := readBody(response, true, true)
+	assert.Nil(err)
+	assert.Equal(body, string(read))
+	assert.Equal(int64(len(compressed)), wireSize)
+	assert.Equal(int64(len(body)), decodedSize)
+	assert.Empty(response.Header.Get("Content-Encoding"))
+	assert.True(response.Uncompressed)
+
+	read, wireSize, decodedSize, err = readBody(newResponse(), true, false)
+	assert.Nil(err)
+	assert.Nil(read)
+	assert.Equal(int64(len(compressed)), wireSize)
+	assert.Equal(int64(len(body)), decodedSize)
+
+	read, wireSize, decodedSize, err = readBody(newResponse(), false, true)
+	assert.Nil(err)
+	assert.Equal(compressed, read)
+	assert.Equal(wireSize, decodedSize)
+
+	response = &http.Response{Header: http.Header{"Content-Encoding": {"gzip"}}, Body: io.NopCloser(strings.NewReader(""))}
+	_, wireSize, _, err = readBody(response, true, false)
+	assert.Nil(err)
+	assert.Equal(int64(0), wireSize)
+}
+
+func TestLode_RunDrainsBodies(t *testing.T) {
+	assert := assert.New(t)
+	NewClient = defaultNewClient
+	Logger = new(mocks.Log)
+	body := strings.Repeat("lode ", 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") == "gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(gzipped(body))
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	params := timeoutParams(server.URL)
+	params.MaxRequests = 2
+	lode := New(params)
+
+	lode.Run()
+
+	assert.Len(lode.ResponseTimings, 2)
+	for _, responseTiming := range lode.ResponseTimings {
+		assert.Equal(int64(len(body)), responseTiming.Response.DecodedSize)
+		assert.Less(responseTiming.Response.WireSize, responseTiming.Response.DecodedSize)
+		assert.False(responseTiming.Timing.Done.Before(responseTiming.Timing.FirstByte))
+	}
+	assert.True(lode.ResponseTimings[1].Timing.Connection.Reused)
+}
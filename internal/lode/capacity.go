@@ -0,0 +1,300 @@
+package lode
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/report"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CapacityStepped = "step"
+	CapacityBinary  = "binary"
+)
+
+// minAchievedRate is the share of the target rate a step must reach. A service that can't keep up
+// makes requests queue behind the concurrency limit, which lowers the rate rather than failing requests.
+const minAchievedRate = 0.9
+
+// CapacityParams searches for the highest request rate a service sustains within an SLO,
+// running a test at each rate with the request defined by Params.
+type CapacityParams struct {
+	Params       Params
+	Search       string
+	MinFreq      int
+	MaxFreq      int
+	StepFreq     int // between steps, and how close a binary search gets
+	StepTime     time.Duration
+	StepWarmup   time.Duration // at the start of each step, left out of its statistics
+	Percentile   float64
+	MaxLatency   time.Duration
+	MaxErrorRate float64 // percentage of requests
+	interrupt    <-chan struct{}
+}
+
+// CapacityStep is the result of testing one request rate
+type CapacityStep struct {
+	Freq        int
+	RequestRate float64
+	Latency     time.Duration // at the capacity percentile
+	ErrorRate   float64
+	Failures    []string // how the step missed the SLO
+}
+
+func (s CapacityStep) Passed() bool {
+	return len(s.Failures) == 0
+}
+
+type CapacityResult struct {
+	Params      CapacityParams
+	Steps       []CapacityStep // in the order they were run
+	Interrupted bool           // the search was stopped part way through a step, which was left out
+}
+
+var runCapacityStep = func(params Params, interrupt <-chan struct{}) TestReport {
+	lode := New(params)
+	lode.Interrupt = interrupt
+	lode.Run()
+	// each step has its own client, so its connections shouldn't be left open for the next step to compete with
+	if closer, ok := lode.Client.(idleConnectionCloser); ok {
+		closer.CloseIdleConnections()
+	}
+	return NewTestReport(lode)
+}
+
+func (c CapacityParams) Validate() {
+	var errors []string
+
+	if c.Search != CapacityStepped && c.Search != CapacityBinary {
+		errors = append(errors, "invalid search - valid options are step and binary")
+	}
+	if c.MinFreq < 1 || c.StepFreq < 1 {
+		errors = append(errors, "minfreq and stepfreq must be provided as positive integers")
+	}
+	if c.MaxFreq <= c.MinFreq {
+		errors = append(errors, "maxfreq must be greater than minfreq")
+	}
+	if c.StepTime <= 0 {
+		errors = append(errors, "steptime must be provided")
+	}
+	if c.StepWarmup < 0 {
+		errors = append(errors, "stepwarmup must not be negative")
+	}
+	if c.Percentile <= 0 || c.Percentile > 100 {
+		errors = append(errors, "percentile must be greater than 0 and at most 100")
+	}
+	if c.MaxLatency <= 0 {
+		errors = append(errors, "maxlatency must be provided")
+	}
+	if c.MaxErrorRate < 0 || c.MaxErrorRate > 100 {
+		errors = append(errors, "maxerrorrate must be between 0 and 100")
+	}
+	if len(errors) != 0 {
+		Logger.Panicf("Invalid capacity search:\n%s\n", strings.Join(errors, "\n"))
+	}
+}
+
+// Run listens for an interrupt or SIGTERM for the whole search, which stops it after the steps so far
+func (c CapacityParams) Run() CapacityResult {
+	interrupt, stopListening := notifyInterrupt()
+	defer stopListening()
+	return c.search(interrupt)
+}
+
+func (c CapacityParams) search(interrupt <-chan struct{}) (result CapacityResult) {
+	c.Validate()
+	result.Params = c
+	c.interrupt = interrupt
+	if c.Search == CapacityBinary {
+		c.binarySearch(&result)
+	} else {
+		c.steppedSearch(&result)
+	}
+	return
+}
+
+// steppedSearch raises the rate by StepFreq until a step misses the SLO
+func (c CapacityParams) steppedSearch(result *CapacityResult) {
+	for freq := c.MinFreq; freq <= c.MaxFreq && !result.Interrupted; freq += c.StepFreq {
+		if !c.runStep(result, freq).Passed() {
+			return
+		}
+	}
+}
+
+// binarySearch narrows the range between a rate that meets the SLO and one that doesn't, down to StepFreq
+func (c CapacityParams) binarySearch(result *CapacityResult) {
+	if !c.runStep(result, c.MinFreq).Passed() || result.Interrupted || c.runStep(result, c.MaxFreq).Passed() {
+		return
+	}
+	passing, failing := c.MinFreq, c.MaxFreq
+	for failing-passing > c.StepFreq && !result.Interrupted {
+		freq := (passing + failing) / 2
+		if c.runStep(result, freq).Passed() {
+			passing = freq
+		} else {
+			failing = freq
+		}
+	}
+}
+
+// runStep runs a step and records it. An interrupted step only ran for part of its time, so it is left out,
+// and the search stops after it.
+func (c CapacityParams) runStep(result *CapacityResult, freq int) CapacityStep {
+	testReport := runCapacityStep(c.stepParams(freq), c.interrupt)
+	select {
+	case <-c.interrupt:
+		result.Interrupted = true
+		return CapacityStep{}
+	default:
+	}
+	step := c.measure(freq, testReport)
+	result.Steps = append(result.Steps, step)
+	Logger.Printf("Step %d: %s\n", len(result.Steps), c.stepSummary(step))
+	return step
+}
+
+func (c CapacityParams) stepParams(freq int) Params {
+	params := c.Params
+	params.Freq, params.Delay = freq, 0
+	params.MaxTime, params.MaxRequests = c.StepTime, 0
+	params.Warmup, params.WarmupRequests = c.StepWarmup, 0
+	params.OutFile = ""
+	return params
+}
+
+// resolution is what step latencies are truncated to
+func (c CapacityParams) resolution() time.Duration {
+	resolution, _ := c.Params.TimingResolution()
+	return resolution
+}
+
+func (c CapacityParams) measure(freq int, testReport TestReport) CapacityStep {
+	measured := testReport.ResponseTimings.Measured()
+	step := CapacityStep{
+		Freq:        freq,
+		RequestRate: testReport.RequestRate,
+		Latency:     report.BuildLatencyPercentiles(measured.Timings(), []float64{c.Percentile}, c.resolution()).Data[c.Percentile],
+	}
+	if len(measured) > 0 {
+		errorCount := 0
+		for _, response := range measured.Responses() {
+			if response.Failed() {
+				errorCount++
+			}
+		}
+		step.ErrorRate = float64(errorCount) / float64(len(measured)) * 100
+	}
+
+	if step.Latency > c.MaxLatency {
+		step.Failures = append(step.Failures, fmt.Sprintf("%s percentile %s over %s",
+			report.FormatPercentile(c.Percentile), report.FormatLatency(step.Latency, c.resolution()), c.MaxLatency))
+	}
+	if step.ErrorRate > c.MaxErrorRate {
+		step.Failures = append(step.Failures, fmt.Sprintf("errors %.2f%% over %s%%", step.ErrorRate, strconv.FormatFloat(c.MaxErrorRate, 'f', -1, 64)))
+	}
+	if step.RequestRate < float64(freq)*minAchievedRate {
+		step.Failures = append(step.Failures, fmt.Sprintf("achieved %.0f%% of the target rate", step.RequestRate/float64(freq)*100))
+	}
+	return step
+}
+
+func (c CapacityParams) stepSummary(step CapacityStep) string {
+	summary := fmt.Sprintf("%d rps target, %.2f achieved, %s percentile %s, %.2f%% errors - ",
+		step.Freq, step.RequestRate, report.FormatPercentile(c.Percentile), report.FormatLatency(step.Latency, c.resolution()), step.ErrorRate)
+	if step.Passed() {
+		return summary + "pass"
+	}
+	return summary + "fail (" + strings.Join(step.Failures, ", ") + ")"
+}
+
+// MaxSustainable is the fastest step that met the SLO
+func (r CapacityResult) MaxSustainable() (best CapacityStep, found bool) {
+	for _, step := range r.Steps {
+		if step.Passed() && (!found || step.Freq > best.Freq) {
+			best, found = step, true
+		}
+	}
+	return
+}
+
+// Knee is the step where latency starts to climb, found as the point furthest below the line
+// from the slowest to the fastest step, with both rate and latency scaled to between 0 and 1.
+func (r CapacityResult) Knee() (knee CapacityStep, found bool) {
+	steps := r.sortedSteps()
+	if len(steps) < 3 {
+		return
+	}
+	first, last := steps[0], steps[len(steps)-1]
+	minLatency, maxLatency := first.Latency, first.Latency
+	for _, step := range steps {
+		if step.Latency < minLatency {
+			minLatency = step.Latency
+		}
+		if step.Latency > maxLatency {
+			maxLatency = step.Latency
+		}
+	}
+	if maxLatency == minLatency {
+		return
+	}
+	furthest := 0.0
+	for _, step := range steps[1 : len(steps)-1] {
+		rate := float64(step.Freq-first.Freq) / float64(last.Freq-first.Freq)
+		latency := float64(step.Latency-minLatency) / float64(maxLatency-minLatency)
+		if distance := rate - latency; distance > furthest {
+			knee, found, furthest = step, true, distance
+		}
+	}
+	return
+}
+
+func (r CapacityResult) sortedSteps() []CapacityStep {
+	steps := append([]CapacityStep{}, r.Steps...)
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].Freq < steps[j].Freq
+	})
+	return steps
+}
+
+func (r CapacityResult) String() string {
+	params := r.Params
+	percentile := report.FormatPercentile(params.Percentile)
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("\nCapacity search: %s %s (%s percentile under %s, errors under %s%%)\n\n",
+		params.Params.Method, params.Params.Url, percentile, params.MaxLatency, strconv.FormatFloat(params.MaxErrorRate, 'f', -1, 64)))
+
+	builder.WriteString(fmt.Sprintf("%-6s %10s %12s %9s %8s  %s\n", "Step", "Target rps", "Achieved rps", percentile, "Errors", "Result"))
+	for i, step := range r.Steps {
+		result := "pass"
+		if !step.Passed() {
+			result = "fail - " + strings.Join(step.Failures, ", ")
+		}
+		builder.WriteString(fmt.Sprintf("%-6d %10d %12.2f %9s %7.2f%%  %s\n",
+			i+1, step.Freq, step.RequestRate, report.FormatLatency(step.Latency, params.resolution()), step.ErrorRate, result))
+	}
+
+	if r.Interrupted {
+		builder.WriteString(fmt.Sprintf("\nInterrupted - the search stopped after %d steps\n", len(r.Steps)))
+	}
+	if best, found := r.MaxSustainable(); found {
+		builder.WriteString(fmt.Sprintf("\nMax sustainable throughput: %d rps (%.2f achieved, %s percentile %s)\n",
+			best.Freq, best.RequestRate, percentile, report.FormatLatency(best.Latency, params.resolution())))
+	} else {
+		builder.WriteString(fmt.Sprintf("\nMax sustainable throughput: none - even %d rps missed the SLO\n", params.MinFreq))
+	}
+	if knee, found := r.Knee(); found {
+		builder.WriteString(fmt.Sprintf("Latency knee: %d rps (%s percentile %s)\n", knee.Freq, percentile, report.FormatLatency(knee.Latency, params.resolution())))
+	}
+	return builder.String()
+}
+
+// ExitWithCode fails when no rate met the SLO
+func (r CapacityResult) ExitWithCode() {
+	if _, found := r.MaxSustainable(); !found {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,208 @@
+package lode
+
+import (
+	"github.com/JamesBalazs/lode/internal/lode/mocks"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/JamesBalazs/lode/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var capacityParams = CapacityParams{
+	Params:       Params{Url: "https://www.example.com", Method: "GET", Concurrency: 10},
+	Search:       CapacityStepped,
+	MinFreq:      10,
+	MaxFreq:      100,
+	StepFreq:     10,
+	StepTime:     10 * time.Second,
+	StepWarmup:   time.Second,
+	Percentile:   99,
+	MaxLatency:   100 * time.Millisecond,
+	MaxErrorRate: 1,
+}
+
+// fakeCapacity serves up to 50 rps, with latency climbing from 40 rps
+func fakeCapacity(t *testing.T) (stepParams *[]Params) {
+	stepParams = &[]Params{}
+	original := runCapacityStep
+	t.Cleanup(func() { runCapacityStep = original })
+	runCapacityStep = func(params Params, _ <-chan struct{}) TestReport {
+		*stepParams = append(*stepParams, params)
+		latency := 20 * time.Millisecond
+		if params.Freq > 30 {
+			latency += time.Duration(params.Freq-30) * 5 * time.Millisecond
+		}
+		rate := float64(params.Freq)
+		if rate > 50 {
+			rate = 50
+		}
+		var timings responseTimings.ResponseTimings
+		for i := 0; i < 100; i++ {
+			timings = append(timings, responseTimings.ResponseTiming{
+				Response: &responseTimings.Response{StatusCode: 200},
+				Timing:   &responseTimings.Timing{ConnectStart: time.Unix(0, 0), Done: time.Unix(0, int64(latency))},
+			})
+		}
+		return TestReport{RequestRate: rate, ResponseCount: len(timings), ResponseTimings: timings}
+	}
+	return
+}
+
+func quietCapacityLogger() {
+	logMock := new(mocks.Log)
+	logMock.On("Printf", "Step %d: %s\n", mock.Anything, mock.Anything)
+	Logger = logMock
+}
+
+func TestCapacityParams_Run_Stepped(t *testing.T) {
+	quietCapacityLogger()
+	stepParams := fakeCapacity(t)
+
+	result := capacityParams.Run()
+
+	assert.Len(t, result.Steps, 5)
+	assert.Equal(t, []string{"99th percentile 120ms over 100ms"}, result.Steps[4].Failures)
+	best, found := result.MaxSustainable()
+	assert.True(t, found)
+	assert.Equal(t, 40, best.Freq)
+
+	step := (*stepParams)[0]
+	assert.Equal(t, 10, step.Freq)
+	assert.Equal(t, 10*time.Second, step.MaxTime)
+	assert.Equal(t, time.Second, step.Warmup)
+	assert.Equal(t, 0, step.MaxRequests)
+}
+
+func TestCapacityParams_Run_Binary(t *testing.T) {
+	quietCapacityLogger()
+	fakeCapacity(t)
+	params := capacityParams
+	params.Search = CapacityBinary
+	params.MaxLatency = 80 * time.Millisecond
+	params.StepFreq = 1
+
+	result := params.Run()
+
+	freqs := []int{}
+	for _, step := range result.Steps {
+		freqs = append(freqs, step.Freq)
+	}
+	assert.Equal(t, []int{10, 100, 55, 32, 43, 37, 40, 41, 42}, freqs)
+	best, _ := result.MaxSustainable()
+	assert.Equal(t, 42, best.Freq)
+}
+
+func TestCapacityParams_Run_Interrupted(t *testing.T) {
+	quietCapacityLogger()
+	fakeCapacity(t)
+	fake := runCapacityStep
+	interrupt := make(chan struct{})
+	runCapacityStep = func(params Params, stepInterrupt <-chan struct{}) TestReport {
+		assert.Equal(t, (<-chan struct{})(interrupt), stepInterrupt)
+		if params.Freq == 30 {
+			close(interrupt)
+		}
+		return fake(params, stepInterrupt)
+	}
+
+	result := capacityParams.search(interrupt)
+
+	assert.True(t, result.Interrupted)
+	assert.Len(t, result.Steps, 2)
+	assert.Contains(t, result.String(), "Interrupted - the search stopped after 2 steps")
+}
+
+type idleClient struct {
+	mocks.Client
+	closed bool
+}
+
+func (c *idleClient) CloseIdleConnections() {
+	c.closed = true
+}
+
+func TestRunCapacityStep_ClosesIdleConnections(t *testing.T) {
+	client := new(idleClient)
+	client.On("Do", mock.Anything).Return(&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil)
+	NewClient = func(Params) types.HttpClientInt {
+		return client
+	}
+	defer func() { NewClient = defaultNewClient }()
+	Logger = new(mocks.Log)
+	stepParams := params
+	stepParams.Freq, stepParams.Delay = 100, 0
+
+	runCapacityStep(stepParams, nil)
+
+	assert.True(t, client.closed)
+}
+
+func TestCapacityParams_Measure(t *testing.T) {
+	failedTiming := responseTimings.ResponseTiming{
+		Response: &responseTimings.Response{StatusCode: 503},
+		Timing:   &responseTimings.Timing{ConnectStart: time.Unix(0, 0), Done: time.Unix(0, 1_000_000)},
+	}
+	warmupTiming := failedTiming
+	warmupTiming.Warmup = true
+	testReport := TestReport{
+		RequestRate:     8,
+		ResponseTimings: responseTimings.ResponseTimings{responseTiming, failedTiming, warmupTiming},
+	}
+
+	step := capacityParams.measure(10, testReport)
+
+	assert.Equal(t, 50.0, step.ErrorRate)
+	assert.Equal(t, []string{"errors 50.00% over 1%", "achieved 80% of the target rate"}, step.Failures)
+	assert.False(t, step.Passed())
+}
+
+func TestCapacityResult_Knee(t *testing.T) {
+	result := CapacityResult{Steps: []CapacityStep{
+		{Freq: 10, Latency: 20 * time.Millisecond},
+		{Freq: 30, Latency: 22 * time.Millisecond},
+		{Freq: 20, Latency: 21 * time.Millisecond},
+		{Freq: 40, Latency: 120 * time.Millisecond},
+		{Freq: 50, Latency: 200 * time.Millisecond},
+	}}
+
+	knee, found := result.Knee()
+
+	assert.True(t, found)
+	assert.Equal(t, 30, knee.Freq)
+
+	_, found = CapacityResult{Steps: result.Steps[:2]}.Knee()
+	assert.False(t, found)
+}
+
+func TestCapacityResult_String(t *testing.T) {
+	quietCapacityLogger()
+	fakeCapacity(t)
+
+	output := capacityParams.Run().String()
+
+	assert.Contains(t, output, "Capacity search: GET https://www.example.com (99th percentile under 100ms, errors under 1%)\n")
+	assert.Contains(t, output, "Step   Target rps Achieved rps      99th   Errors  Result\n")
+	assert.Contains(t, output, "1              10        10.00      20ms    0.00%  pass\n")
+	assert.Contains(t, output, "5              50        50.00     120ms    0.00%  fail - 99th percentile 120ms over 100ms\n")
+	assert.Contains(t, output, "Max sustainable throughput: 40 rps (40.00 achieved, 99th percentile 70ms)\n")
+	assert.Contains(t, output, "Latency knee: 30 rps (99th percentile 20ms)\n")
+}
+
+func TestCapacityParams_Validate(t *testing.T) {
+	logMock := new(mocks.Log)
+	Logger = logMock
+	params := capacityParams
+	params.Search = "random"
+	params.MaxFreq = 10
+	params.MaxLatency = 0
+
+	logMock.On("Panicf", "Invalid capacity search:\n%s\n",
+		"invalid search - valid options are step and binary\nmaxfreq must be greater than minfreq\nmaxlatency must be provided").Once()
+	params.Validate()
+	logMock.AssertExpectations(t)
+}
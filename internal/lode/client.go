@@ -0,0 +1,136 @@
+package lode
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/types"
+	"golang.org/x/net/http2"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+var NewClient = func(params Params) types.HttpClientInt {
+	tlsConfig, err := params.TlsConfig()
+	if err != nil {
+		Logger.Panicf("Error creating TLS config: %s", err.Error())
+	}
+	dialer, err := params.Dialer()
+	if err != nil {
+		Logger.Panicf("Error creating dialer: %s", err.Error())
+	}
+	proxyUrl, err := params.ProxyUrl()
+	if err != nil {
+		Logger.Panicf("Error parsing proxy: %s", err.Error())
+	}
+
+	client := http.Client{Timeout: params.Timeout, CheckRedirect: params.checkRedirect}
+	if params.NewConnPerRequest {
+		return &newConnClient{
+			client:       client,
+			newTransport: func() http.RoundTripper { return newTransport(params, dialer, proxyUrl, tlsConfig) },
+		}
+	}
+
+	client.Transport = newTransport(params, dialer, proxyUrl, tlsConfig)
+	return &client
+}
+
+func newTransport(params Params, dialer *Dialer, proxyUrl *url.URL, tlsConfig *tls.Config) http.RoundTripper {
+	if params.H2cPriorKnowledge {
+		return &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	if dialer.unixSocket == "" {
+		setProxy(transport, dialer, proxyUrl, params.NoProxy)
+	} else {
+		transport.Proxy = nil
+	}
+	transport.DisableKeepAlives = params.DisableKeepAlives || params.NewConnPerRequest
+	if params.MaxIdleConns > 0 {
+		transport.MaxIdleConnsPerHost = params.MaxIdleConns
+	}
+	transport.MaxConnsPerHost = params.MaxConnsPerHost
+	if params.TlsTimeout > 0 {
+		transport.TLSHandshakeTimeout = params.TlsTimeout
+	}
+	transport.ResponseHeaderTimeout = params.HeaderTimeout
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig.Clone()
+	}
+
+	// a non-nil, empty TLSNextProto stops the transport from upgrading to HTTP/2, which would
+	// otherwise also add h2 to an ALPN list that was set without it
+	if params.ForceHttp1 || (len(params.TlsAlpn) > 0 && !containsString(params.TlsAlpn, "h2")) {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	if params.ForceHttp2 {
+		return requireHttp2{transport}
+	}
+	return transport
+}
+
+// requireHttp2 fails requests that the server answered over HTTP/1.x, rather than silently falling back
+type requireHttp2 struct {
+	*http.Transport
+}
+
+func (r requireHttp2) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := r.Transport.RoundTrip(request)
+	if err == nil && response.ProtoMajor != 2 {
+		response.Body.Close()
+		return nil, fmt.Errorf("server responded with %s, but HTTP/2 was required", response.Proto)
+	}
+	return response, err
+}
+
+type idleConnectionCloser interface {
+	CloseIdleConnections()
+}
+
+// newConnClient uses a fresh transport for every request, so no connections or TLS sessions are shared
+type newConnClient struct {
+	client       http.Client
+	newTransport func() http.RoundTripper
+}
+
+func (n *newConnClient) Do(request *http.Request) (*http.Response, error) {
+	transport := n.newTransport()
+	client := n.client
+	client.Transport = transport
+
+	response, err := client.Do(request)
+	if err != nil {
+		closeIdleConnections(transport)
+		return response, err
+	}
+	response.Body = closeTransportOnClose{ReadCloser: response.Body, transport: transport}
+	return response, nil
+}
+
+type closeTransportOnClose struct {
+	io.ReadCloser
+	transport http.RoundTripper
+}
+
+func (c closeTransportOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	closeIdleConnections(c.transport)
+	return err
+}
+
+func closeIdleConnections(transport http.RoundTripper) {
+	if closer, ok := transport.(idleConnectionCloser); ok {
+		closer.CloseIdleConnections()
+	}
+}
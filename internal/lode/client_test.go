@@ -0,0 +1,134 @@
+package lode
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func okHandler(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte("ok"))
+}
+
+func doGet(t *testing.T, client interface {
+	Do(*http.Request) (*http.Response, error)
+}, url string) (*http.Response, error) {
+	request, _ := http.NewRequest("GET", url, nil)
+	response, err := client.Do(request)
+	if err == nil {
+		_, _ = io.ReadAll(response.Body)
+		response.Body.Close()
+	}
+	return response, err
+}
+
+func TestNewClient_Defaults(t *testing.T) {
+	assert := assert.New(t)
+
+	client := defaultNewClient(Params{Timeout: 3 * time.Second}).(*http.Client)
+	transport := client.Transport.(*http.Transport)
+
+	assert.Equal(3*time.Second, client.Timeout)
+	assert.False(transport.DisableKeepAlives)
+	assert.Equal(0, transport.MaxConnsPerHost)
+	assert.True(transport.ForceAttemptHTTP2)
+}
+
+func TestNewClient_ConnectionPool(t *testing.T) {
+	assert := assert.New(t)
+
+	client := defaultNewClient(Params{DisableKeepAlives: true, MaxIdleConns: 4, MaxConnsPerHost: 8}).(*http.Client)
+	transport := client.Transport.(*http.Transport)
+
+	assert.True(transport.DisableKeepAlives)
+	assert.Equal(4, transport.MaxIdleConnsPerHost)
+	assert.Equal(8, transport.MaxConnsPerHost)
+}
+
+func TestNewClient_ForceHttp1(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(okHandler))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	client := defaultNewClient(Params{ForceHttp1: true}).(*http.Client)
+	transport := client.Transport.(*http.Transport)
+	transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	response, err := doGet(t, client, server.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, response.ProtoMajor)
+}
+
+func TestNewClient_ForceHttp2(t *testing.T) {
+	assert := assert.New(t)
+	http1Server := httptest.NewTLSServer(http.HandlerFunc(okHandler))
+	defer http1Server.Close()
+	http2Server := httptest.NewUnstartedServer(http.HandlerFunc(okHandler))
+	http2Server.EnableHTTP2 = true
+	http2Server.StartTLS()
+	defer http2Server.Close()
+
+	for _, server := range []*httptest.Server{http1Server, http2Server} {
+		client := defaultNewClient(Params{ForceHttp2: true}).(*http.Client)
+		transport := client.Transport.(requireHttp2)
+		transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+		transport.TLSClientConfig.NextProtos = nil
+
+		response, err := doGet(t, client, server.URL)
+
+		if server == http1Server {
+			assert.ErrorContains(err, "server responded with HTTP/1.1, but HTTP/2 was required")
+		} else {
+			assert.Nil(err)
+			assert.Equal(2, response.ProtoMajor)
+		}
+	}
+}
+
+func TestNewClient_H2cPriorKnowledge(t *testing.T) {
+	server := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(okHandler), &http2.Server{}))
+	defer server.Close()
+
+	client := defaultNewClient(Params{H2cPriorKnowledge: true})
+	response, err := doGet(t, client, server.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, response.ProtoMajor)
+}
+
+func TestNewClient_NewConnPerRequest(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(okHandler))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	for _, newConnPerRequest := range []bool{false, true} {
+		atomic.StoreInt32(&connections, 0)
+		client := defaultNewClient(Params{NewConnPerRequest: newConnPerRequest})
+
+		for i := 0; i < 3; i++ {
+			_, err := doGet(t, client, server.URL)
+			assert.Nil(t, err)
+		}
+
+		expected := int32(1)
+		if newConnPerRequest {
+			expected = 3
+		}
+		assert.Equal(t, expected, atomic.LoadInt32(&connections))
+	}
+}
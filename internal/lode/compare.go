@@ -0,0 +1,90 @@
+package lode
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/report"
+	"sort"
+	"strings"
+)
+
+type Comparison struct {
+	Original TestReport
+	Rerun    TestReport
+}
+
+func (l *Lode) Compare(original TestReport) {
+	comparison := Comparison{Original: original, Rerun: NewTestReport(l)}
+	Logger.Printf("%s", comparison.String())
+}
+
+func (c Comparison) String() string {
+	builder := strings.Builder{}
+	row := func(label string, original string, rerun string, change string) {
+		builder.WriteString(strings.TrimRight(fmt.Sprintf("%-28s %-12s %-12s %s", label, original, rerun, change), " ") + "\n")
+	}
+
+	builder.WriteString("\nComparison with original run:\n")
+	row("", "Original", "Rerun", "Change")
+	row("Target:", c.Original.Target, c.Rerun.Target, "")
+	row("Requests made:", fmt.Sprint(c.Original.ResponseCount), fmt.Sprint(c.Rerun.ResponseCount),
+		percentageChange(float64(c.Original.ResponseCount), float64(c.Rerun.ResponseCount)))
+	row("Time taken:", c.Original.Duration.String(), c.Rerun.Duration.String(),
+		percentageChange(float64(c.Original.Duration), float64(c.Rerun.Duration)))
+	row("Requests per second (avg):", fmt.Sprint(c.Original.RequestRate), fmt.Sprint(c.Rerun.RequestRate),
+		percentageChange(c.Original.RequestRate, c.Rerun.RequestRate))
+
+	// scored against the rerun's thresholds, which the original is compared to
+	if threshold := c.Rerun.Params.Apdex; threshold > 0 {
+		originalApdex := report.BuildApdex(c.Original.ResponseTimings.Measured(), threshold).Score()
+		rerunApdex := c.Rerun.Apdex().Score()
+		row(fmt.Sprintf("Apdex (T=%s):", threshold), fmt.Sprintf("%.2f", originalApdex), fmt.Sprintf("%.2f", rerunApdex),
+			percentageChange(originalApdex, rerunApdex))
+	}
+	if c.Rerun.Params.Slo != "" {
+		rerunCompliance := c.Rerun.SloCompliance()
+		originalCompliance := report.BuildSloCompliance(c.Original.ResponseTimings.Measured(), rerunCompliance.Slo)
+		row("SLO budget consumed:", fmt.Sprintf("%.1f%%", originalCompliance.BudgetConsumed()), fmt.Sprintf("%.1f%%", rerunCompliance.BudgetConsumed()),
+			percentageChange(originalCompliance.BudgetConsumed(), rerunCompliance.BudgetConsumed()))
+	}
+
+	builder.WriteString("\nResponse code breakdown:\n")
+	originalStatuses, rerunStatuses := c.Original.StatusHistogram(), c.Rerun.StatusHistogram()
+	for _, statusCode := range unionKeys(originalStatuses.Data, rerunStatuses.Data) {
+		row(fmt.Sprintf("%d:", statusCode), fmt.Sprintf("%dx", originalStatuses.Data[statusCode]),
+			fmt.Sprintf("%dx", rerunStatuses.Data[statusCode]), "")
+	}
+
+	builder.WriteString("\nPercentile latency breakdown:\n")
+	// the original is summarised at the rerun's percentiles, so the rows line up
+	rerunLatencies := c.Rerun.LatencyPercentiles()
+	originalLatencies := report.BuildLatencyPercentiles(c.Original.ResponseTimings.Measured().Timings(), rerunLatencies.Percentiles,
+		c.Original.resolution())
+	for _, percentile := range rerunLatencies.Percentiles {
+		originalLatency, rerunLatency := originalLatencies.Data[percentile], rerunLatencies.Data[percentile]
+		row(report.FormatPercentile(percentile)+":", report.FormatLatency(originalLatency, originalLatencies.Resolution),
+			report.FormatLatency(rerunLatency, rerunLatencies.Resolution),
+			percentageChange(float64(originalLatency), float64(rerunLatency)))
+	}
+
+	return builder.String()
+}
+
+func percentageChange(original float64, rerun float64) string {
+	if original == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", (rerun-original)/original*100)
+}
+
+func unionKeys(a map[int]int, b map[int]int) (keys []int) {
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Ints(keys)
+	return
+}
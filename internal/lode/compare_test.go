@@ -0,0 +1,122 @@
+package lode
+
+import (
+	"github.com/JamesBalazs/lode/internal/files/rundata"
+	"github.com/JamesBalazs/lode/internal/lode/mocks"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestComparison_String(t *testing.T) {
+	slowResponseTiming := responseTimings.ResponseTiming{
+		Response: &responseTimings.Response{Status: "503 Service Unavailable", StatusCode: 503},
+		Timing: &responseTimings.Timing{
+			ConnectStart: time.Unix(0, 1_000_000),
+			Done:         time.Unix(0, 5_000_000),
+		},
+	}
+	comparison := Comparison{
+		Original: TestReport{
+			Target:          "GET https://www.example.com",
+			Duration:        10 * time.Second,
+			ResponseCount:   2,
+			RequestRate:     0.2,
+			ResponseTimings: responseTimings.ResponseTimings{responseTiming, responseTiming},
+		},
+		Rerun: TestReport{
+			Target:          "GET https://www.example.com",
+			Duration:        10 * time.Second,
+			ResponseCount:   2,
+			RequestRate:     0.2,
+			ResponseTimings: responseTimings.ResponseTimings{responseTiming, slowResponseTiming},
+		},
+	}
+
+	output := comparison.String()
+
+	assert.Contains(t, output, `
+Comparison with original run:
+                             Original     Rerun        Change
+Target:                      GET https://www.example.com GET https://www.example.com
+Requests made:               2            2            +0.0%
+Time taken:                  10s          10s          +0.0%
+Requests per second (avg):   0.2          0.2          +0.0%
+
+Response code breakdown:
+200:                         2x           1x
+503:                         0x           1x
+
+Percentile latency breakdown:
+50th:                        2ms          2ms          +0.0%
+66th:                        2ms          3ms          +50.0%
+`)
+	assert.Contains(t, output, "100th:                       2ms          4ms          +100.0%\n")
+}
+
+func TestComparison_StringOriginalResolution(t *testing.T) {
+	original := responseTimings.ResponseTimings{{
+		Response: &responseTimings.Response{StatusCode: 200},
+		Timing:   &responseTimings.Timing{ConnectStart: time.Unix(0, 0), Done: time.Unix(0, 2_500_000)},
+	}}
+	runData := rundata.RunDataV2{Params: rundata.Params{Resolution: "us"}, ResponseCount: 1, ResponseTimings: original}
+	comparison := Comparison{
+		Original: TestReportFromRunData(runData),
+		Rerun:    TestReport{ResponseCount: 2, ResponseTimings: responseTimings.ResponseTimings{responseTiming, responseTiming}},
+	}
+
+	// each run is shown at the resolution it was recorded at
+	assert.Contains(t, comparison.String(), "50th:                        2.500ms      2ms          -20.0%\n")
+}
+
+func TestComparison_StringNoOriginalResponses(t *testing.T) {
+	comparison := Comparison{
+		Rerun: TestReport{
+			ResponseCount:   1,
+			ResponseTimings: responseTimings.ResponseTimings{responseTiming},
+		},
+	}
+
+	output := comparison.String()
+
+	assert.Contains(t, output, "Requests made:               0            1            -\n")
+	assert.Contains(t, output, "200:                         0x           1x\n")
+}
+
+func TestLode_Compare(t *testing.T) {
+	logMock := new(mocks.Log)
+	Logger = logMock
+	lode := New(params)
+	lode.ResponseTimings = responseTimings.ResponseTimings{responseTiming}
+	logMock.On("Printf", "%s", mock.MatchedBy(func(str string) bool {
+		result, _ := regexp.MatchString("Comparison with original run", str)
+		return result
+	})).Once()
+
+	lode.Compare(TestReport{})
+
+	logMock.AssertExpectations(t)
+}
+
+func TestComparison_StringApdexAndSlo(t *testing.T) {
+	slowResponseTiming := responseTimings.ResponseTiming{
+		Response: &responseTimings.Response{Status: "200 OK", StatusCode: 200},
+		Timing:   &responseTimings.Timing{ConnectStart: time.Unix(0, 0), Done: time.Unix(0, 10_000_000)},
+	}
+	comparison := Comparison{
+		Original: TestReport{ResponseCount: 2, ResponseTimings: responseTimings.ResponseTimings{responseTiming, responseTiming}},
+		Rerun: TestReport{
+			ResponseCount:   2,
+			ResponseTimings: responseTimings.ResponseTimings{responseTiming, slowResponseTiming},
+			Params:          Params{Apdex: 2 * time.Millisecond, Slo: "90% under 5ms"},
+		},
+	}
+
+	output := comparison.String()
+
+	assert.Contains(t, output, "Apdex (T=2ms):               1.00         0.50         -50.0%\n")
+	assert.Contains(t, output, "SLO budget consumed:         0.0%         500.0%       -\n")
+}
@@ -0,0 +1,47 @@
+package lode
+
+import (
+	"github.com/JamesBalazs/lode/internal/report"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"time"
+)
+
+const (
+	// defaultConvergePercentile is watched when only the convergence width is set
+	defaultConvergePercentile = 99
+	// minConvergeRequests are made before the first check, and between checks early on
+	minConvergeRequests = 100
+)
+
+// convergence stops a run once the confidence interval of a percentile is narrower than a target width.
+// Each check resamples every latency so far, so checks get further apart as the run grows.
+type convergence struct {
+	percentile float64
+	width      time.Duration
+	nextCheck  int
+	resolution time.Duration
+}
+
+func (p Params) convergence() *convergence {
+	if p.ConvergeWidth == 0 {
+		return nil
+	}
+	percentile := p.ConvergePercentile
+	if percentile == 0 {
+		percentile = defaultConvergePercentile
+	}
+	resolution, _ := p.TimingResolution()
+	return &convergence{percentile: percentile, width: p.ConvergeWidth, nextCheck: minConvergeRequests, resolution: resolution}
+}
+
+func (c *convergence) converged(measuredCount int, timings responseTimings.ResponseTimings) bool {
+	if measuredCount < c.nextCheck {
+		return false
+	}
+	step := measuredCount / 10
+	if step < minConvergeRequests {
+		step = minConvergeRequests
+	}
+	c.nextCheck = measuredCount + step
+	return report.PercentileInterval(timings.Measured().Timings(), c.percentile, c.resolution).Width() <= c.width
+}
@@ -0,0 +1,51 @@
+package lode
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func convergenceTimings(count int, spread time.Duration) (timings responseTimings.ResponseTimings) {
+	for i := 0; i < count; i++ {
+		latency := 100*time.Millisecond + time.Duration(i)*spread
+		timings = append(timings, responseTimings.ResponseTiming{Timing: &responseTimings.Timing{GotConn: time.Unix(0, 0), Done: time.Unix(0, 0).Add(latency)}})
+	}
+	return
+}
+
+func TestParams_Convergence(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(Params{ConvergePercentile: 90}.convergence())
+	assert.Equal(&convergence{percentile: 99, width: time.Millisecond, nextCheck: 100, resolution: time.Millisecond}, Params{ConvergeWidth: time.Millisecond}.convergence())
+	assert.Equal(&convergence{percentile: 90, width: time.Millisecond, nextCheck: 100, resolution: time.Millisecond}, Params{ConvergeWidth: time.Millisecond, ConvergePercentile: 90}.convergence())
+}
+
+func TestConvergence_Converged(t *testing.T) {
+	assert := assert.New(t)
+	converge := Params{ConvergeWidth: 5 * time.Millisecond}.convergence()
+	timings := convergenceTimings(1000, 0)
+
+	assert.False(converge.converged(99, timings[:99]))
+	assert.True(converge.converged(100, timings[:100]))
+
+	converge = Params{ConvergeWidth: time.Millisecond}.convergence()
+	timings = convergenceTimings(1000, time.Millisecond)
+
+	assert.False(converge.converged(100, timings[:100]))
+	assert.Equal(200, converge.nextCheck)
+	assert.False(converge.converged(199, timings[:199]))
+	converge.nextCheck = 1000
+	assert.False(converge.converged(1000, timings))
+	assert.Equal(1100, converge.nextCheck)
+}
+
+func TestConvergence_IgnoresWarmup(t *testing.T) {
+	converge := Params{ConvergeWidth: 5 * time.Millisecond}.convergence()
+	timings := convergenceTimings(100, 0)
+	timings = append(timings, responseTimings.ResponseTiming{Timing: &responseTimings.Timing{GotConn: time.Unix(0, 0), Done: time.Unix(10, 0)}, Warmup: true})
+
+	assert.True(t, converge.converged(100, timings))
+}
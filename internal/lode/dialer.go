@@ -0,0 +1,197 @@
+package lode
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Dialer resolves and connects to targets, applying any --resolve overrides, custom DNS server,
+// address family preference and source addresses. Lookups made here are still reported through httptrace.
+type Dialer struct {
+	net.Dialer
+	overrides  map[string]string // host:port, or host:* for any port, to IP address
+	dnsServer  string
+	noDnsCache bool
+	preferIpv4 bool
+	preferIpv6 bool
+	unixSocket string
+	sources    []net.IP
+	nextSource *uint32
+	network    *NetworkConditions
+	resolver   *net.Resolver
+}
+
+func (p Params) Dialer() (*Dialer, error) {
+	dialer := &Dialer{
+		Dialer:     net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		overrides:  make(map[string]string),
+		noDnsCache: p.NoDnsCache,
+		preferIpv4: p.PreferIpv4,
+		preferIpv6: p.PreferIpv6,
+		nextSource: new(uint32),
+	}
+	if p.DialTimeout > 0 {
+		dialer.Timeout = p.DialTimeout
+	}
+	var err error
+	if _, dialer.unixSocket, err = p.Target(); err != nil {
+		return nil, err
+	}
+
+	for _, resolve := range p.Resolve {
+		parts := strings.SplitN(resolve, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || net.ParseIP(strings.Trim(parts[2], "[]")) == nil {
+			return nil, fmt.Errorf("invalid resolve %q - expected host:port:addr, where addr is an IP address", resolve)
+		}
+		dialer.overrides[strings.ToLower(parts[0])+":"+parts[1]] = strings.Trim(parts[2], "[]")
+	}
+
+	if p.DnsServer != "" {
+		host, port, err := net.SplitHostPort(p.DnsServer)
+		if err != nil {
+			host, port = strings.Trim(p.DnsServer, "[]"), "53"
+		}
+		if net.ParseIP(host) == nil {
+			return nil, fmt.Errorf("invalid dnsserver %q - expected an IP address, optionally with a port", p.DnsServer)
+		}
+		dialer.dnsServer = net.JoinHostPort(host, port)
+	}
+	if dialer.sources, err = sourceIps(p.SourceAddrs); err != nil {
+		return nil, err
+	}
+	if dialer.network, err = p.NetworkConditions(); err != nil {
+		return nil, err
+	}
+	dialer.resolver = dialer.newResolver()
+	return dialer, nil
+}
+
+// sourceIps expands each source address, which may be an IP or the name of an interface to use all the addresses of.
+func sourceIps(sourceAddrs []string) (ips []net.IP, err error) {
+	for _, sourceAddr := range sourceAddrs {
+		if ip := net.ParseIP(sourceAddr); ip != nil {
+			ips = append(ips, ip)
+			continue
+		}
+		networkInterface, err := net.InterfaceByName(sourceAddr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sourceaddr %q - expected an IP address or interface name", sourceAddr)
+		}
+		addrs, _ := networkInterface.Addrs()
+		found := false
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLinkLocalUnicast() {
+				ips, found = append(ips, ipNet.IP), true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("interface %q has no usable addresses for sourceaddr", sourceAddr)
+		}
+	}
+	return
+}
+
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.dial(ctx, network, address)
+	if err != nil || d.network == nil {
+		return conn, err
+	}
+	return d.network.emulate(ctx, conn), nil
+}
+
+func (d *Dialer) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.unixSocket != "" {
+		return d.Dialer.DialContext(ctx, "unix", d.unixSocket)
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	dialer := d.sourceDialer()
+	if override, ok := d.override(host, port); ok {
+		return dialer.DialContext(ctx, network, net.JoinHostPort(override, port))
+	}
+	if (d.dnsServer == "" && !d.noDnsCache && !d.preferIpv4 && !d.preferIpv6) || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.Timeout)
+	defer cancel()
+	resolver := d.resolver
+	if d.noDnsCache {
+		resolver = d.newResolver()
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	d.sortAddrs(addrs)
+
+	for _, addr := range addrs {
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// sourceDialer binds to the next source address in turn, so connections are spread across them.
+func (d *Dialer) sourceDialer() *net.Dialer {
+	if len(d.sources) == 0 {
+		return &d.Dialer
+	}
+	dialer := d.Dialer
+	index := atomic.AddUint32(d.nextSource, 1) - 1
+	dialer.LocalAddr = &net.TCPAddr{IP: d.sources[index%uint32(len(d.sources))]}
+	return &dialer
+}
+
+func (d *Dialer) override(host, port string) (string, bool) {
+	host = strings.ToLower(host)
+	if addr, ok := d.overrides[host+":"+port]; ok {
+		return addr, true
+	}
+	addr, ok := d.overrides[host+":*"]
+	return addr, ok
+}
+
+// newResolver uses Go's built-in DNS client, rather than the system resolver, when a custom server
+// or fresh lookups are wanted. Each resolver dedupes its own in-flight lookups, so with noDnsCache
+// every connection gets a new one.
+func (d *Dialer) newResolver() *net.Resolver {
+	resolver := &net.Resolver{PreferGo: d.noDnsCache || d.dnsServer != ""}
+	if d.dnsServer != "" {
+		resolver.Dial = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			// the request's trace would time the DNS server connection as the TCP connect, and the A and AAAA
+			// lookups dial at the same time, so only the deadline is carried over
+			dialCtx, cancel := context.Background(), context.CancelFunc(func() {})
+			if deadline, ok := ctx.Deadline(); ok {
+				dialCtx, cancel = context.WithDeadline(dialCtx, deadline)
+			}
+			defer cancel()
+			return d.Dialer.DialContext(dialCtx, network, d.dnsServer)
+		}
+	}
+	return resolver
+}
+
+// sortAddrs moves addresses of the preferred family to the front, keeping the resolver's order otherwise.
+func (d *Dialer) sortAddrs(addrs []net.IPAddr) {
+	if !d.preferIpv4 && !d.preferIpv6 {
+		return
+	}
+	sort.SliceStable(addrs, func(i, j int) bool {
+		return d.preferred(addrs[i].IP) && !d.preferred(addrs[j].IP)
+	})
+}
+
+func (d *Dialer) preferred(ip net.IP) bool {
+	isIpv4 := ip.To4() != nil
+	return (d.preferIpv4 && isIpv4) || (d.preferIpv6 && !isIpv4)
+}
@@ -0,0 +1,203 @@
+package lode
+
+import (
+	"context"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startDnsServer answers every A query with 127.0.0.1, counting the queries it receives.
+func startDnsServer(t *testing.T) (addr string, queries *int32) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	queries = new(int32)
+
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			var request dnsmessage.Message
+			if request.Unpack(buffer[:n]) != nil || len(request.Questions) == 0 {
+				continue
+			}
+			question := request.Questions[0]
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: request.ID, Response: true, Authoritative: true},
+				Questions: request.Questions,
+			}
+			if question.Type == dnsmessage.TypeA {
+				atomic.AddInt32(queries, 1)
+				response.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+				}}
+			}
+			packed, _ := response.Pack()
+			_, _ = conn.WriteTo(packed, from)
+		}
+	}()
+	return conn.LocalAddr().String(), queries
+}
+
+func hostEchoServer(t *testing.T) (port string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host))
+	}))
+	t.Cleanup(server.Close)
+	_, port, _ = net.SplitHostPort(server.Listener.Addr().String())
+	return
+}
+
+func TestParams_Dialer_Errors(t *testing.T) {
+	for _, test := range []struct {
+		params   Params
+		expected string
+	}{
+		{Params{Resolve: []string{"example.com:443"}}, `invalid resolve "example.com:443" - expected host:port:addr, where addr is an IP address`},
+		{Params{Resolve: []string{"example.com:443:not-an-ip"}}, `invalid resolve "example.com:443:not-an-ip" - expected host:port:addr, where addr is an IP address`},
+		{Params{DnsServer: "dns.example.com"}, `invalid dnsserver "dns.example.com" - expected an IP address, optionally with a port`},
+	} {
+		dialer, err := test.params.Dialer()
+
+		assert.Nil(t, dialer)
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func TestParams_Dialer(t *testing.T) {
+	assert := assert.New(t)
+
+	dialer, err := Params{
+		Resolve:   []string{"Example.com:443:10.0.0.1", "example.com:*:[::1]"},
+		DnsServer: "1.1.1.1",
+	}.Dialer()
+
+	assert.Nil(err)
+	assert.Equal(map[string]string{"example.com:443": "10.0.0.1", "example.com:*": "::1"}, dialer.overrides)
+	assert.Equal("1.1.1.1:53", dialer.dnsServer)
+
+	addr, ok := dialer.override("EXAMPLE.COM", "443")
+	assert.True(ok)
+	assert.Equal("10.0.0.1", addr)
+	addr, ok = dialer.override("example.com", "8080")
+	assert.True(ok)
+	assert.Equal("::1", addr)
+	_, ok = dialer.override("other.com", "443")
+	assert.False(ok)
+
+	dialer, _ = Params{DnsServer: "[::1]:5353"}.Dialer()
+	assert.Equal("[::1]:5353", dialer.dnsServer)
+}
+
+func TestDialer_SortAddrs(t *testing.T) {
+	ipv6, ipv4, otherIpv6 := net.IPAddr{IP: net.ParseIP("::1")}, net.IPAddr{IP: net.ParseIP("127.0.0.1")}, net.IPAddr{IP: net.ParseIP("::2")}
+
+	for _, test := range []struct {
+		dialer   Dialer
+		expected []net.IPAddr
+	}{
+		{Dialer{}, []net.IPAddr{ipv6, ipv4, otherIpv6}},
+		{Dialer{preferIpv4: true}, []net.IPAddr{ipv4, ipv6, otherIpv6}},
+		{Dialer{preferIpv6: true}, []net.IPAddr{ipv6, otherIpv6, ipv4}},
+	} {
+		addrs := []net.IPAddr{ipv6, ipv4, otherIpv6}
+		test.dialer.sortAddrs(addrs)
+
+		assert.Equal(t, test.expected, addrs)
+	}
+}
+
+func TestNewClient_Resolve(t *testing.T) {
+	assert := assert.New(t)
+	port := hostEchoServer(t)
+
+	client := defaultNewClient(Params{Resolve: []string{"lode.test:" + port + ":127.0.0.1"}})
+	request, _ := http.NewRequest("GET", "http://lode.test:"+port, nil)
+	response, err := client.Do(request)
+
+	assert.Nil(err)
+	body := make([]byte, 64)
+	n, _ := response.Body.Read(body)
+	response.Body.Close()
+	assert.Equal("lode.test:"+port, string(body[:n]))
+}
+
+func TestNewClient_DnsServer(t *testing.T) {
+	assert := assert.New(t)
+	dnsServer, queries := startDnsServer(t)
+	port := hostEchoServer(t)
+
+	client := defaultNewClient(Params{DnsServer: dnsServer, NoDnsCache: true, DisableKeepAlives: true})
+	for i := 0; i < 2; i++ {
+		timing := &responseTimings.Timing{}
+		request, _ := http.NewRequest("GET", "http://lode.test:"+port, nil)
+		request = request.WithContext(httptrace.WithClientTrace(context.Background(), responseTimings.NewTrace(timing)))
+		response, err := client.Do(request)
+		assert.Nil(err)
+		response.Body.Close()
+		assert.False(timing.DnsDone.IsZero())
+		assert.Equal([]string{"127.0.0.1"}, timing.Connection.ResolvedAddrs)
+	}
+	assert.Equal(int32(2), atomic.LoadInt32(queries))
+}
+
+func TestDialer_NewResolverDialsWithoutTrace(t *testing.T) {
+	assert := assert.New(t)
+	dnsServer, _ := startDnsServer(t)
+	dialer, _ := Params{DnsServer: dnsServer}.Dialer()
+	connects := 0
+	ctx, cancel := context.WithTimeout(httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		ConnectStart: func(_, _ string) { connects++ },
+	}), time.Second)
+	defer cancel()
+
+	conn, err := dialer.newResolver().Dial(ctx, "udp", "")
+
+	assert.Nil(err)
+	defer conn.Close()
+	assert.Equal(0, connects)
+}
+
+func TestParams_Dialer_SourceAddrs(t *testing.T) {
+	assert := assert.New(t)
+
+	dialer, err := Params{SourceAddrs: []string{"127.0.0.1", "lo"}}.Dialer()
+
+	assert.Nil(err)
+	assert.True(dialer.sources[0].Equal(net.ParseIP("127.0.0.1")))
+	assert.GreaterOrEqual(len(dialer.sources), 2)
+
+	_, err = Params{SourceAddrs: []string{"not-an-interface"}}.Dialer()
+	assert.EqualError(err, `invalid sourceaddr "not-an-interface" - expected an IP address or interface name`)
+}
+
+func TestNewClient_SourceAddrs(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		_, _ = w.Write([]byte(host))
+	}))
+	defer server.Close()
+
+	client := defaultNewClient(Params{SourceAddrs: []string{"127.0.0.1", "127.0.0.2"}, NewConnPerRequest: true})
+	var sources []string
+	for i := 0; i < 3; i++ {
+		timing, body := tracedGet(t, client, server.URL)
+		sources = append(sources, body)
+		assert.Contains(timing.Connection.LocalAddr, body+":")
+	}
+
+	assert.Equal([]string{"127.0.0.1", "127.0.0.2", "127.0.0.1"}, sources)
+}
@@ -4,7 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/JamesBalazs/lode/internal/files"
+	"github.com/JamesBalazs/lode/internal/files/rundata"
+	"github.com/JamesBalazs/lode/internal/redact"
+	"github.com/JamesBalazs/lode/internal/report"
 	"github.com/JamesBalazs/lode/internal/responseTimings"
 	"github.com/JamesBalazs/lode/internal/types"
 	"gopkg.in/yaml.v3"
@@ -19,28 +23,36 @@ import (
 	"time"
 )
 
+var Version = "dev"
 var Logger types.LoggerInt = log.New(os.Stdout, "", 0)
 var NewRequest = http.NewRequest
-var NewClient = func(timeout time.Duration) types.HttpClientInt {
-	return &http.Client{Timeout: timeout}
-}
 
 type Lode struct {
+	Params          Params
 	Client          types.HttpClientInt
 	Request         *http.Request
+	RequestBody     string
 	Concurrency     int
 	MaxRequests     int
 	ExitCode        int
 	TargetDelay     time.Duration
 	MaxTime         time.Duration
+	Resolution      time.Duration // what timings are truncated to
 	StartTime       time.Time
+	MeasureStart    time.Time                  // when the last warm-up response arrived, if there was a warm-up
+	Converged       bool                       // stopped early, once the chosen percentile's confidence interval was narrow enough
+	Concurrencies   []report.ConcurrencySample // how an adaptive concurrency limit changed over the run
 	FinishTime      time.Time
 	ResponseTimings responseTimings.ResponseTimings
+	BodyCapture     responseTimings.BodyCapture
+	Grouper         report.Grouper // decides whether response headers are kept when bodies aren't
+	Redactor        redact.Redactor
 	FailFast        bool
 	IgnoreFailures  bool
 	Interactive     bool
 	OutFile         string
 	OutFormat       string
+	Interrupt       <-chan struct{} // ends the run early once closed - without it, the run listens for signals itself
 }
 
 func New(params Params) *Lode {
@@ -50,10 +62,22 @@ func New(params Params) *Lode {
 	if params.Freq != 0 {
 		params.Delay = time.Second / time.Duration(params.Freq)
 	}
+	// record the conditions a named profile stands for in the run file, as profiles may change
+	if network, _ := params.NetworkConditions(); network != nil {
+		params.NetworkProfile, params.NetworkLatency, params.NetworkJitter = network.Profile, network.Latency, network.Jitter
+		params.DownloadKbps, params.UploadKbps, params.DropRate = network.DownloadKbps, network.UploadKbps, network.DropRate
+	}
 	params.Validate()
+	resolution, _ := params.TimingResolution()
 
-	body := files.ReaderFromFileOrString(params.File, params.Body)
-	req, err := NewRequest(params.Method, params.Url, body)
+	body, err := io.ReadAll(files.ReaderFromFileOrString(params.File, params.Body))
+	if err != nil {
+		Logger.Panicf("Error reading request body: %s", err.Error())
+		return nil
+	}
+
+	target, _, _ := params.Target()
+	req, err := NewRequest(params.Method, target, strings.NewReader(string(body)))
 	if err != nil {
 		Logger.Panicf("Error creating request: %s", err.Error())
 		return nil
@@ -69,13 +93,35 @@ func New(params Params) *Lode {
 		outFormat = "yaml"
 	}
 
+	redactor, err := params.Redactor()
+	if err != nil {
+		Logger.Panicf("Error creating redactor: %s", err.Error())
+		return nil
+	}
+
+	// an empty or invalid groupby gives a zero Grouper, which groups nothing
+	grouper, _ := report.NewGrouper(params.GroupBy)
+
+	bodyCapture := responseTimings.BodyCapture{
+		MaxBytes:   params.MaxBodySize,
+		FailedOnly: params.CaptureFailed,
+		Every:      params.CaptureEvery,
+		HashOnly:   params.BodyHashOnly,
+	}
+
 	return &Lode{
+		Params:         params,
 		TargetDelay:    params.Delay,
-		Client:         NewClient(params.Timeout),
+		Client:         NewClient(params),
 		Request:        req,
+		RequestBody:    string(body),
 		Concurrency:    params.Concurrency,
 		MaxRequests:    params.MaxRequests,
 		MaxTime:        params.MaxTime,
+		Resolution:     resolution,
+		BodyCapture:    bodyCapture,
+		Grouper:        grouper,
+		Redactor:       redactor,
 		FailFast:       params.FailFast,
 		IgnoreFailures: params.IgnoreFailures,
 		OutFile:        params.OutFile,
@@ -92,42 +138,80 @@ func (l *Lode) Run() {
 	defer l.setFinishTime()
 
 	result := make(chan responseTimings.ResponseTiming, 1024)
-	l.closeOnSigterm(result)
+	// the result channel isn't closed on an interrupt, as workers may still be sending to it
+	interrupt, stopListening := l.interrupt()
+	defer stopListening()
+
+	// with adaptive concurrency, every worker is started but only those within the limit make requests
+	limit := l.Params.adaptiveLimit(l.StartTime)
+	if limit != nil {
+		defer l.recordConcurrencies(limit)
+	}
 
 	for i := 0; i < l.Concurrency; i++ {
-		go l.work(trigger, stop, result)
+		go l.work(i+1, limit, trigger, stop, result)
 	}
 
 	startTime := time.Now()
-	endTime := startTime.Add(l.MaxTime).UnixNano()
+	// the warm-up period comes on top of maxtime, and maxrequests only counts measured requests
+	warmupEnd := startTime.Add(l.Params.Warmup)
+	endTime := warmupEnd.Add(l.MaxTime).UnixNano()
 	checkMaxRequests := l.MaxRequests > 0
 	checkMaxTime := l.MaxTime > 0
 	responseCount := 0
+	measuredCount := 0
+	// maxrequests and maxtime still cap a run that doesn't converge
+	converge := l.Params.convergence()
 
-	for response := range result {
+	for {
+		response, ok := nextResponse(result, interrupt)
+		if !ok {
+			return
+		}
 		responseCount++
+		if responseCount <= l.Params.WarmupRequests || (l.Params.Warmup > 0 && response.Timing.Start.Before(warmupEnd)) {
+			response.Warmup = true
+			l.MeasureStart = time.Now()
+		} else {
+			measuredCount++
+		}
+		if l.Interactive || l.WriteFile() {
+			// redact the full bodies before they're trimmed, as a trimmed JSON body can't be parsed to find fields to redact
+			l.BodyCapture.Measure(response)
+			l.Redactor.ResponseTiming(response)
+			l.BodyCapture.Apply(response, responseCount)
+		}
 		l.ResponseTimings = append(l.ResponseTimings, response)
+		if limit != nil {
+			limit.observe(response)
+		}
 
-		if !l.IgnoreFailures && (response.Response.StatusCode < 100 || response.Response.StatusCode >= 400) {
+		if !response.Warmup && !l.IgnoreFailures && response.Response.Failed() {
 			l.ExitCode = 1
 		}
 
-		if (checkMaxRequests && responseCount >= l.MaxRequests) || (checkMaxTime && time.Now().UnixNano() >= endTime) {
+		if (checkMaxRequests && measuredCount >= l.MaxRequests) || (checkMaxTime && time.Now().UnixNano() >= endTime) {
+			return
+		}
+		if converge != nil && !response.Warmup && converge.converged(measuredCount, l.ResponseTimings) {
+			l.Converged = true
 			return
 		}
 	}
 }
 
-func (l Lode) work(trigger <-chan time.Time, stop chan struct{}, result chan responseTimings.ResponseTiming) {
+func (l Lode) work(workerId int, limit *adaptiveLimit, trigger <-chan time.Time, stop chan struct{}, result chan responseTimings.ResponseTiming) {
 	ctx := context.Background()
 	for {
+		if limit != nil && !limit.acquire(workerId) {
+			return
+		}
 		select {
 		case <-trigger:
-			response, timing := l.makeAndTimeRequest(ctx)
-			result <- responseTimings.ResponseTiming{
-				Response: response,
-				Timing:   timing,
+			if limit != nil {
+				limit.begin()
 			}
+			result <- l.makeAndTimeRequest(ctx, workerId)
 		case <-stop:
 			return
 		}
@@ -139,8 +223,16 @@ func (l Lode) stop(ticker *time.Ticker, stop chan struct{}) {
 	close(stop)
 }
 
+func (l *Lode) recordConcurrencies(limit *adaptiveLimit) {
+	limit.stop()
+	l.Concurrencies = limit.samples()
+}
+
 func (l *Lode) Report() {
 	report := NewTestReport(l)
+	if len(report.ThresholdsMissed()) > 0 {
+		l.ExitCode = 1
+	}
 
 	if l.WriteFile() {
 		var marshalFunc func(v any) ([]byte, error)
@@ -161,12 +253,9 @@ func (l *Lode) Report() {
 			panic("invalid outFormat")
 		}
 
-		runData := report.ToRunData()
-		if data, err := marshalFunc(runData); err != nil {
+		if data, err := marshalFunc(l.RunData(report)); err != nil {
 			Logger.Panicf("error marshalling outfile: %s", err.Error())
-		} else if file, err := os.Create(l.OutFile); err != nil {
-			Logger.Panicf("error creating outfile: %s", err.Error())
-		} else if _, err = file.Write(data); err != nil {
+		} else if err = files.WriteFile(l.OutFile, data); err != nil {
 			Logger.Panicf("error writing outfile: %s", err.Error())
 		}
 	}
@@ -174,67 +263,204 @@ func (l *Lode) Report() {
 	RunReport(report)
 }
 
+// RunData is what the run file records, with the request body itself in place of any body file,
+// so a rerun sends the same body wherever it runs
+func (l *Lode) RunData(report TestReport) rundata.RunDataV2 {
+	runData := report.ToRunData()
+	runData.Params.Body, runData.Params.File = l.RequestBody, ""
+	l.Redactor.Params(&runData.Params)
+	return runData
+}
+
 func RunReport(report TestReport) {
 	output := report.Output()
 	if report.Interactive {
 		output += "Requests:\n"
-		Logger.Printf(output)
+		Logger.Printf("%s", output)
 		prompt := newInteractivePrompt(output, report.ResponseTimings)
 		_, _, err := prompt.Run()
 		if err != nil {
 			Logger.Panicln(err.Error())
 		}
 	} else {
-		Logger.Printf(output)
+		Logger.Printf("%s", output)
+	}
+}
+
+// interrupt is closed to end the run early. It's Interrupt if there is one, otherwise it's closed on an interrupt
+// or SIGTERM. The returned func stops listening, once the run is over.
+func (l Lode) interrupt() (<-chan struct{}, func()) {
+	if l.Interrupt != nil {
+		return l.Interrupt, func() {}
+	}
+	return notifyInterrupt()
+}
+
+// nextResponse waits for a response, reporting false instead if the run is interrupted
+func nextResponse(result <-chan responseTimings.ResponseTiming, interrupt <-chan struct{}) (responseTimings.ResponseTiming, bool) {
+	select {
+	case response := <-result:
+		return response, true
+	case <-interrupt:
+		return responseTimings.ResponseTiming{}, false
 	}
 }
 
-func (l Lode) closeOnSigterm(channel chan responseTimings.ResponseTiming) {
-	sigterm := make(chan os.Signal, 1)
-	signal.Notify(sigterm, os.Interrupt, syscall.SIGTERM)
+// notifyInterrupt returns a channel that is closed on an interrupt or SIGTERM, and a func that stops listening
+// for them, so they end the process again.
+func notifyInterrupt() (<-chan struct{}, func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	interrupt, done := make(chan struct{}), make(chan struct{})
 	go func() {
-		<-sigterm
-		close(channel)
+		select {
+		case <-signals:
+			close(interrupt)
+		case <-done:
+		}
 	}()
+	return interrupt, func() {
+		signal.Stop(signals)
+		close(done)
+	}
 }
 
 func (l *Lode) setFinishTime() {
 	l.FinishTime = time.Now()
 }
 
-func (l Lode) makeAndTimeRequest(ctx context.Context) (result *responseTimings.Response, timing *responseTimings.Timing) {
+func (l Lode) makeAndTimeRequest(ctx context.Context, workerId int) responseTimings.ResponseTiming {
 	var err error
 	var response *http.Response
-	timing = &responseTimings.Timing{}
+	timing := &responseTimings.Timing{Start: time.Now()}
+	timing.SetResolution(l.Resolution)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	trace := responseTimings.NewTrace(timing)
-	request := l.Request.WithContext(httptrace.WithClientTrace(ctx, trace))
+	ctx = responseTimings.WithTiming(ctx, timing)
+	request, decompress := acceptGzip(l.Request.WithContext(httptrace.WithClientTrace(ctx, trace)))
+	if l.Request.GetBody != nil {
+		// each request needs its own reader, as the body is consumed when it is sent
+		if request.Body, err = l.Request.GetBody(); err != nil {
+			Logger.Panicf("Error creating request body: %s", err.Error())
+		}
+	}
 	response, err = l.Client.Do(request)
 	timing.Done = time.Now()
-	if err != nil {
+	if err != nil && isTimeout(err) {
+		return l.timedOut(request, workerId, timing, err)
+	} else if err != nil && errors.Is(err, errConnectionDropped) {
+		return l.dropped(request, workerId, timing, err)
+	} else if err != nil {
 		Logger.Panicf("Error during request: %s", err.Error())
-	} else if l.FailFast && (response.StatusCode < 100 || response.StatusCode >= 400) {
+	} else if l.FailFast && responseTimings.FailedStatus(response.StatusCode) {
 		Logger.Fatalf("Got non-success status code: %d", response.StatusCode)
 	}
 
-	result = &responseTimings.Response{
+	timing.Connection.Protocol = response.Proto
+	if response.TLS != nil {
+		// covers reused connections, where no handshake was traced
+		timing.Connection.SetTls(*response.TLS)
+	}
+
+	requestResult := newRequestResult(request, workerId)
+	if response.Request != nil {
+		requestResult.Url = response.Request.URL.String()
+	}
+
+	result := &responseTimings.Response{
 		Status:        response.Status,
 		StatusCode:    response.StatusCode,
 		ContentLength: response.ContentLength,
 	}
 
-	if l.Interactive || l.WriteFile() {
+	// bodies are always drained, so the connection can be reused and Done includes the transfer
+	keep := l.Interactive || l.WriteFile()
+	if response.Body != nil {
+		if l.Params.IdleBodyTimeout > 0 {
+			response.Body = newIdleTimeoutBody(response.Body, l.Params.IdleBodyTimeout, cancel)
+		}
+		defer response.Body.Close()
+
 		var body []byte
-		body, err = io.ReadAll(response.Body)
-		if err != nil {
-			Logger.Panicf("Error reading body: %s", err.Error())
+		body, result.WireSize, result.DecodedSize, err = readBody(response, decompress, keep)
+		timing.Done = time.Now()
+		if err != nil && isTimeout(err) {
+			result.Error, result.TimeoutPhase = err.Error(), responseTimings.PhaseBody
+			l.failFastOnTimeout(result.TimeoutPhase)
+		} else if err != nil {
+			// a dropped or reset connection, or a body cut short, fails the request but not the run
+			result.Error = err.Error()
+			if l.FailFast {
+				Logger.Fatalf("Error reading body: %s", err.Error())
+			}
 		}
-		response.Body.Close()
+		result.Body = string(body)
+	}
 
+	// grouping by a header needs it even when responses aren't kept
+	if keep || l.Grouper.NeedsHeaders() {
 		result.Header = responseTimings.Header{HttpHeader: response.Header}
-		result.Body = string(body)
 	}
+	if keep {
+		// the request as configured, without the Accept-Encoding header added for compression
+		requestResult.Header = responseTimings.Header{HttpHeader: l.Request.Header}
+		requestResult.Body = l.RequestBody
+	}
+
+	return responseTimings.ResponseTiming{
+		Request:  requestResult,
+		Response: result,
+		Timing:   timing,
+	}
+}
+
+func newRequestResult(request *http.Request, workerId int) *responseTimings.Request {
+	return &responseTimings.Request{
+		Method:   request.Method,
+		Url:      request.URL.String(),
+		WorkerId: workerId,
+		Attempt:  1,
+	}
+}
+
+// timedOut records a request that timed out before a response arrived as a failure with no status code,
+// classified by the phase it was in.
+func (l Lode) timedOut(request *http.Request, workerId int, timing *responseTimings.Timing, err error) responseTimings.ResponseTiming {
+	phase := timing.TimeoutPhase()
+	l.failFastOnTimeout(phase)
+	return l.failed(request, workerId, timing, &responseTimings.Response{
+		Status:       "Timeout (" + phase + ")",
+		Error:        err.Error(),
+		TimeoutPhase: phase,
+	})
+}
 
-	return
+// dropped records a request whose connection was dropped by network emulation as a failure with no status code.
+func (l Lode) dropped(request *http.Request, workerId int, timing *responseTimings.Timing, err error) responseTimings.ResponseTiming {
+	if l.FailFast {
+		Logger.Fatalf("Request failed: %s", err.Error())
+	}
+	return l.failed(request, workerId, timing, &responseTimings.Response{Status: "Connection dropped", Error: err.Error()})
+}
+
+func (l Lode) failed(request *http.Request, workerId int, timing *responseTimings.Timing, response *responseTimings.Response) responseTimings.ResponseTiming {
+	requestResult := newRequestResult(request, workerId)
+	if l.Interactive || l.WriteFile() {
+		requestResult.Header = responseTimings.Header{HttpHeader: l.Request.Header}
+		requestResult.Body = l.RequestBody
+	}
+	return responseTimings.ResponseTiming{
+		Request:  requestResult,
+		Response: response,
+		Timing:   timing,
+	}
+}
+
+func (l Lode) failFastOnTimeout(phase string) {
+	if l.FailFast {
+		Logger.Fatalf("Request timed out during %s", phase)
+	}
 }
 
 func (l *Lode) ExitWithCode() {
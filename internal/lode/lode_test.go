@@ -1,20 +1,27 @@
 package lode
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"github.com/JamesBalazs/lode/internal/lode/mocks"
+	"github.com/JamesBalazs/lode/internal/redact"
 	"github.com/JamesBalazs/lode/internal/responseTimings"
 	"github.com/JamesBalazs/lode/internal/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
 	"time"
 )
 
+var defaultNewClient = NewClient
+
 var params = Params{
 	Url:         "https://www.example.com",
 	Method:      "GET",
@@ -35,25 +42,34 @@ func TestNewLode_ReturnsLode(t *testing.T) {
 	logMock := new(mocks.Log)
 	Logger = logMock
 	clientMock := new(mocks.Client)
-	NewClient = func(timeout time.Duration) types.HttpClientInt {
+	NewClient = func(Params) types.HttpClientInt {
 		return clientMock
 	}
 	NewRequest = func(method, url string, body io.Reader) (*http.Request, error) {
 		return expectedRequest, nil
 	}
+	// a local copy, as other tests change the shared params
+	lodeParams := params
+	lodeParams.Timeout, lodeParams.Headers = time.Second, nil
+	expectedParams := lodeParams
+	expectedParams.Delay = time.Second
+	expectedRedactor, _ := expectedParams.Redactor()
 	expectedLode := &Lode{
-		TargetDelay:     params.Delay,
+		Params:          expectedParams,
+		TargetDelay:     time.Second,
 		Client:          clientMock,
 		Request:         expectedRequest,
 		Concurrency:     1,
 		MaxRequests:     1,
 		MaxTime:         0,
+		Resolution:      time.Millisecond,
 		StartTime:       time.Time{},
 		ResponseTimings: responseTimings.ResponseTimings(nil),
+		Redactor:        expectedRedactor,
 		OutFormat:       "json",
 	}
 
-	lode := New(params)
+	lode := New(lodeParams)
 
 	assert.Equal(expectedLode, lode)
 }
@@ -63,7 +79,7 @@ func TestNewLode_ErrorCreatingRequest(t *testing.T) {
 	logMock := new(mocks.Log)
 	Logger = logMock
 	clientMock := new(mocks.Client)
-	NewClient = func(timeout time.Duration) types.HttpClientInt {
+	NewClient = func(Params) types.HttpClientInt {
 		return clientMock
 	}
 	NewRequest = func(string, string, io.Reader) (*http.Request, error) {
@@ -75,9 +91,7 @@ func TestNewLode_ErrorCreatingRequest(t *testing.T) {
 
 	assert.Nil(lode)
 	logMock.AssertExpectations(t)
-	NewClient = func(timeout time.Duration) types.HttpClientInt {
-		return &http.Client{Timeout: timeout}
-	}
+	NewClient = defaultNewClient
 	NewRequest = http.NewRequest
 }
 
@@ -101,10 +115,11 @@ func TestNewLode_SetsHeaders(t *testing.T) {
 }
 
 func TestNewLode_DefaultTimeout(t *testing.T) {
-	params.Timeout = 0
+	lodeParams := params
+	lodeParams.Timeout = 0
 	expectedTimeout := 5 * time.Second
 
-	lode := New(params)
+	lode := New(lodeParams)
 	client := lode.Client.(*http.Client)
 
 	assert.Equal(t, expectedTimeout, client.Timeout)
@@ -112,7 +127,7 @@ func TestNewLode_DefaultTimeout(t *testing.T) {
 
 func TestLode_RunDoesRequest(t *testing.T) {
 	clientMock := new(mocks.Client)
-	NewClient = func(timeout time.Duration) types.HttpClientInt {
+	NewClient = func(Params) types.HttpClientInt {
 		return clientMock
 	}
 	response := &http.Response{
@@ -131,14 +146,35 @@ func TestLode_RunDoesRequest(t *testing.T) {
 	logMock.AssertExpectations(t)
 }
 
+func TestLode_RunInterrupted(t *testing.T) {
+	clientMock := new(mocks.Client)
+	NewClient = func(Params) types.HttpClientInt {
+		return clientMock
+	}
+	clientMock.On("Do", mock.Anything).Return(&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil)
+	Logger = new(mocks.Log)
+	interrupt := make(chan struct{})
+	runParams := params
+	runParams.Freq, runParams.Delay, runParams.MaxRequests, runParams.MaxTime = 10, 0, 0, time.Minute
+	lode := New(runParams)
+	lode.Interrupt = interrupt
+
+	start := time.Now()
+	time.AfterFunc(200*time.Millisecond, func() { close(interrupt) })
+	lode.Run()
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.NotEmpty(t, lode.ResponseTimings)
+}
+
 func TestLode_RunInteractiveStoresBodyAndHeaders(t *testing.T) {
 	assert := assert.New(t)
 	clientMock := new(mocks.Client)
-	NewClient = func(timeout time.Duration) types.HttpClientInt {
+	NewClient = func(Params) types.HttpClientInt {
 		return clientMock
 	}
 	body := "someBody"
-	header := http.Header{"Set-Cookie": {`abc="def"`}}
+	header := http.Header{"Content-Type": {"text/plain"}}
 	response := &http.Response{
 		StatusCode:    200,
 		ContentLength: 3,
@@ -159,9 +195,188 @@ func TestLode_RunInteractiveStoresBodyAndHeaders(t *testing.T) {
 	assert.Equal(responseTimings.Header{HttpHeader: header}, lode.ResponseTimings[0].Response.Header)
 }
 
+func TestLode_RunGroupByHeaderStoresHeaders(t *testing.T) {
+	assert := assert.New(t)
+	clientMock := new(mocks.Client)
+	NewClient = func(Params) types.HttpClientInt {
+		return clientMock
+	}
+	header := http.Header{"X-Served-By": {"web-1"}}
+	response := &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader("someBody")),
+		Header:     header,
+	}
+	clientMock.On("Do", mock.Anything).Return(response, nil).Once()
+	logMock := new(mocks.Log)
+	Logger = logMock
+	oldGroupBy := params.GroupBy
+	defer func() { params.GroupBy = oldGroupBy }()
+	params.GroupBy = "header:X-Served-By"
+
+	lode := New(params)
+	lode.Run()
+
+	clientMock.AssertExpectations(t)
+	assert.True(lode.Grouper.NeedsHeaders())
+	assert.Equal(responseTimings.Header{HttpHeader: header}, lode.ResponseTimings[0].Response.Header)
+	assert.Equal("", lode.ResponseTimings[0].Response.Body)
+}
+
+func TestLode_RunInteractiveStoresRequest(t *testing.T) {
+	assert := assert.New(t)
+	clientMock := new(mocks.Client)
+	NewClient = func(Params) types.HttpClientInt {
+		return clientMock
+	}
+	var sentBodies []string
+	response := &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}
+	clientMock.On("Do", mock.Anything).Run(func(args mock.Arguments) {
+		body, _ := io.ReadAll(args.Get(0).(*http.Request).Body)
+		sentBodies = append(sentBodies, string(body))
+	}).Return(response, nil).Twice()
+	logMock := new(mocks.Log)
+	Logger = logMock
+	oldParams := params
+	defer func() { params = oldParams }()
+	params.Method, params.Body, params.MaxRequests, params.Freq = "POST", "someBody", 2, 100
+	params.Headers = []string{"Content-Type=text/plain"}
+	lode := New(params)
+	lode.Interactive = true
+
+	lode.Run()
+
+	clientMock.AssertExpectations(t)
+	assert.Equal([]string{"someBody", "someBody"}, sentBodies)
+	request := lode.ResponseTimings[0].Request
+	assert.Equal("POST", request.Method)
+	assert.Equal("https://www.example.com", request.Url)
+	assert.Equal(http.Header{"Content-Type": {"text/plain"}}, request.Header.HttpHeader)
+	assert.Equal("someBody", request.Body)
+	assert.Equal(int64(8), request.BodySize)
+	assert.Equal(1, request.WorkerId)
+	assert.Equal(1, request.Attempt)
+	assert.False(lode.ResponseTimings[0].Timing.Start.IsZero())
+}
+
+func TestLode_RunInteractiveRedactsHeaders(t *testing.T) {
+	assert := assert.New(t)
+	clientMock := new(mocks.Client)
+	NewClient = func(Params) types.HttpClientInt {
+		return clientMock
+	}
+	response := &http.Response{
+		StatusCode:    200,
+		ContentLength: 3,
+		Body:          io.NopCloser(strings.NewReader("abc")),
+		Header:        http.Header{"Set-Cookie": {`abc="def"`}, "X-Session": {"123"}},
+	}
+	clientMock.On("Do", mock.Anything).Return(response, nil).Once()
+	logMock := new(mocks.Log)
+	Logger = logMock
+	oldRedactHeaders := params.RedactHeaders
+	defer func() { params.RedactHeaders = oldRedactHeaders }()
+	params.RedactHeaders = []string{"X-Session"}
+	lode := New(params)
+	lode.Interactive = true
+
+	lode.Run()
+
+	clientMock.AssertExpectations(t)
+	assert.Equal(http.Header{"Set-Cookie": {redact.Mask}, "X-Session": {redact.Mask}}, lode.ResponseTimings[0].Response.Header.HttpHeader)
+}
+
+func TestLode_RunInteractiveAppliesBodyCapture(t *testing.T) {
+	assert := assert.New(t)
+	clientMock := new(mocks.Client)
+	NewClient = func(Params) types.HttpClientInt {
+		return clientMock
+	}
+	response := &http.Response{
+		StatusCode:    200,
+		ContentLength: 8,
+		Body:          io.NopCloser(strings.NewReader("someBody")),
+	}
+	clientMock.On("Do", mock.Anything).Return(response, nil).Once()
+	logMock := new(mocks.Log)
+	Logger = logMock
+	oldMaxBodySize := params.MaxBodySize
+	defer func() { params.MaxBodySize = oldMaxBodySize }()
+	params.MaxBodySize = 4
+	lode := New(params)
+	lode.Interactive = true
+
+	lode.Run()
+
+	clientMock.AssertExpectations(t)
+	assert.Equal("some", lode.ResponseTimings[0].Response.Body)
+	assert.Equal(int64(8), lode.ResponseTimings[0].Response.BodySize)
+	assert.True(lode.ResponseTimings[0].Response.BodyTruncated)
+}
+
+func TestLode_ReportRedactsFieldsBeforeTrimmingBodies(t *testing.T) {
+	assert := assert.New(t)
+	clientMock := new(mocks.Client)
+	NewClient = func(Params) types.HttpClientInt {
+		return clientMock
+	}
+	body := `{"token":"supersecret-abcdef","name":"lode"}`
+	response := &http.Response{
+		StatusCode:    200,
+		ContentLength: int64(len(body)),
+		Body:          io.NopCloser(strings.NewReader(body)),
+	}
+	clientMock.On("Do", mock.Anything).Return(response, nil).Once()
+	logMock := new(mocks.Log)
+	logMock.On("Printf", "%s", mock.Anything)
+	Logger = logMock
+	redactParams := params
+	redactParams.MaxBodySize = 30
+	redactParams.RedactFields = []string{"$.token"}
+	redactParams.OutFile = filepath.Join(t.TempDir(), "out.json")
+	lode := New(redactParams)
+
+	lode.Run()
+	lode.Report()
+
+	written, err := os.ReadFile(redactParams.OutFile)
+	assert.Nil(err)
+	assert.NotContains(string(written), "supersecret")
+	assert.True(lode.ResponseTimings[0].Response.BodyTruncated)
+	assert.Equal(int64(len(body)), lode.ResponseTimings[0].Response.BodySize)
+}
+
+func TestLode_RunInteractiveHashesRedactedBodies(t *testing.T) {
+	assert := assert.New(t)
+	clientMock := new(mocks.Client)
+	NewClient = func(Params) types.HttpClientInt {
+		return clientMock
+	}
+	response := &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(`{"token":"supersecret-abcdef"}`)),
+	}
+	clientMock.On("Do", mock.Anything).Return(response, nil).Once()
+	Logger = new(mocks.Log)
+	redactParams := params
+	redactParams.RedactFields = []string{"$.token"}
+	lode := New(redactParams)
+	lode.Interactive = true
+
+	lode.Run()
+
+	stored := lode.ResponseTimings[0].Response
+	assert.NotContains(stored.Body, "supersecret")
+	sum := sha256.Sum256([]byte(stored.Body))
+	assert.Equal("sha256:"+hex.EncodeToString(sum[:]), stored.BodyHash)
+}
+
 func TestLode_RunErrorDoingRequest(t *testing.T) {
 	clientMock := new(mocks.Client)
-	NewClient = func(timeout time.Duration) types.HttpClientInt {
+	NewClient = func(Params) types.HttpClientInt {
 		return clientMock
 	}
 	clientMock.On("Do", mock.Anything).Return(&http.Response{}, errors.New("error doing request"))
@@ -178,7 +393,7 @@ func TestLode_RunErrorDoingRequest(t *testing.T) {
 
 func TestLode_RunFailFast(t *testing.T) {
 	clientMock := new(mocks.Client)
-	NewClient = func(timeout time.Duration) types.HttpClientInt {
+	NewClient = func(Params) types.HttpClientInt {
 		return clientMock
 	}
 	response := &http.Response{
@@ -203,7 +418,7 @@ func TestLode_RunFailFast(t *testing.T) {
 
 func TestLode_RunNonZeroExitCode(t *testing.T) {
 	clientMock := new(mocks.Client)
-	NewClient = func(timeout time.Duration) types.HttpClientInt {
+	NewClient = func(Params) types.HttpClientInt {
 		return clientMock
 	}
 	response := &http.Response{
@@ -223,9 +438,34 @@ func TestLode_RunNonZeroExitCode(t *testing.T) {
 	assert.Equal(t, 1, lode.ExitCode)
 }
 
+func TestLode_RunWarmupRequests(t *testing.T) {
+	assert := assert.New(t)
+	clientMock := new(mocks.Client)
+	NewClient = func(Params) types.HttpClientInt {
+		return clientMock
+	}
+	clientMock.On("Do", mock.Anything).Return(&http.Response{StatusCode: 503, Body: io.NopCloser(strings.NewReader(""))}, nil).Twice()
+	clientMock.On("Do", mock.Anything).Return(&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil).Once()
+	logMock := new(mocks.Log)
+	Logger = logMock
+	warmupParams := params
+	warmupParams.Freq, warmupParams.WarmupRequests = 100, 2
+
+	lode := New(warmupParams)
+	lode.Run()
+
+	clientMock.AssertExpectations(t)
+	assert.Len(lode.ResponseTimings, 3)
+	assert.True(lode.ResponseTimings[0].Warmup)
+	assert.True(lode.ResponseTimings[1].Warmup)
+	assert.False(lode.ResponseTimings[2].Warmup)
+	assert.False(lode.MeasureStart.IsZero())
+	assert.Equal(0, lode.ExitCode)
+}
+
 func TestLode_RunIgnoreFailures(t *testing.T) {
 	clientMock := new(mocks.Client)
-	NewClient = func(timeout time.Duration) types.HttpClientInt {
+	NewClient = func(Params) types.HttpClientInt {
 		return clientMock
 	}
 	response := &http.Response{
@@ -255,7 +495,7 @@ func TestLode_ReportOneRequest(t *testing.T) {
 	lode.ResponseTimings = responseTimings.ResponseTimings{
 		responseTimings.ResponseTiming{Response: &responseTimings.Response{}},
 	}
-	logMock.On("Printf", mock.MatchedBy(func(str string) bool {
+	logMock.On("Printf", "%s", mock.MatchedBy(func(str string) bool {
 		result, _ := regexp.MatchString("Timing breakdown", str)
 		return result
 	})).Once()
@@ -273,7 +513,7 @@ func TestLode_ReportMultipleRequests(t *testing.T) {
 		responseTimings.ResponseTiming{Response: &responseTimings.Response{}, Timing: &responseTimings.Timing{}},
 		responseTimings.ResponseTiming{Response: &responseTimings.Response{}, Timing: &responseTimings.Timing{}},
 	}
-	logMock.On("Printf", mock.MatchedBy(func(str string) bool {
+	logMock.On("Printf", "%s", mock.MatchedBy(func(str string) bool {
 		result1, _ := regexp.MatchString("Response code breakdown", str)
 		result2, _ := regexp.MatchString("Percentile latency breakdown", str)
 		return result1 && result2
@@ -284,12 +524,44 @@ func TestLode_ReportMultipleRequests(t *testing.T) {
 	logMock.AssertExpectations(t)
 }
 
+func TestLode_ReportMissedThresholds(t *testing.T) {
+	logMock := new(mocks.Log)
+	Logger = logMock
+	thresholdParams := params
+	thresholdParams.Apdex, thresholdParams.MinApdex = time.Millisecond, 0.9
+	lode := New(thresholdParams)
+	lode.ResponseTimings = responseTimings.ResponseTimings{responseTiming, responseTiming}
+	logMock.On("Printf", "%s", mock.MatchedBy(func(str string) bool {
+		return strings.Contains(str, "Threshold missed: Apdex 0.50 is below the minimum of 0.90\n")
+	})).Once()
+
+	lode.Report()
+
+	logMock.AssertExpectations(t)
+	assert.Equal(t, 1, lode.ExitCode)
+}
+
+func TestLode_RunDataRecordsBodyFromFile(t *testing.T) {
+	assert := assert.New(t)
+	Logger = new(mocks.Log)
+	bodyFile := filepath.Join(t.TempDir(), "body.json")
+	assert.Nil(os.WriteFile(bodyFile, []byte(`{"name":"lode"}`), 0600))
+	fileParams := params
+	fileParams.File = bodyFile
+	lode := New(fileParams)
+
+	runData := lode.RunData(NewTestReport(lode))
+
+	assert.Equal(`{"name":"lode"}`, runData.Params.Body)
+	assert.Equal("", runData.Params.File)
+}
+
 func TestLode_ReportNoRequests(t *testing.T) {
 	logMock := new(mocks.Log)
 	Logger = logMock
 	lode := New(params)
 	lode.ResponseTimings = responseTimings.ResponseTimings{}
-	logMock.On("Printf", mock.MatchedBy(func(str string) bool {
+	logMock.On("Printf", "%s", mock.MatchedBy(func(str string) bool {
 		result, _ := regexp.MatchString("No requests made...", str)
 		return result
 	})).Once()
@@ -313,7 +585,7 @@ func TestLode_ReportInteractive(t *testing.T) {
 	lode.ResponseTimings = responseTimings.ResponseTimings{
 		responseTimings.ResponseTiming{Response: &responseTimings.Response{}, Timing: &responseTimings.Timing{}},
 	}
-	logMock.On("Printf", mock.MatchedBy(func(str string) bool {
+	logMock.On("Printf", "%s", mock.MatchedBy(func(str string) bool {
 		result1, _ := regexp.MatchString("Response code breakdown", str)
 		result2, _ := regexp.MatchString("Percentile latency breakdown", str)
 		return result1 && result2
@@ -340,7 +612,7 @@ func TestLode_ReportInteractiveError(t *testing.T) {
 	lode.ResponseTimings = responseTimings.ResponseTimings{
 		responseTimings.ResponseTiming{Response: &responseTimings.Response{}, Timing: &responseTimings.Timing{}},
 	}
-	logMock.On("Printf", mock.MatchedBy(func(str string) bool {
+	logMock.On("Printf", "%s", mock.MatchedBy(func(str string) bool {
 		result1, _ := regexp.MatchString("Response code breakdown", str)
 		result2, _ := regexp.MatchString("Percentile latency breakdown", str)
 		return result1 && result2
@@ -0,0 +1,261 @@
+package lode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const NetworkCustom = "custom"
+
+var networkProfiles = map[string]NetworkConditions{
+	"3g":      {Latency: 300 * time.Millisecond, Jitter: 50 * time.Millisecond, DownloadKbps: 1600, UploadKbps: 750},
+	"slow-4g": {Latency: 150 * time.Millisecond, Jitter: 25 * time.Millisecond, DownloadKbps: 4000, UploadKbps: 1500},
+}
+
+var errConnectionDropped = errors.New("connection dropped by network emulation")
+
+// NetworkConditions are emulated on every connection, to see how the target behaves for clients on slower networks
+type NetworkConditions struct {
+	Profile      string
+	Latency      time.Duration // round trip, half added to everything sent and half to everything received
+	Jitter       time.Duration // latency varies by up to this much either way
+	DownloadKbps int
+	UploadKbps   int
+	DropRate     float64 // chance of the connection dropping on each read or write
+}
+
+// NetworkConditions starts from the named profile, if any, and applies any values that were set on top of it.
+// It returns nil if no network emulation was asked for.
+func (p Params) NetworkConditions() (*NetworkConditions, error) {
+	conditions, ok := networkProfiles[p.NetworkProfile]
+	if !ok && p.NetworkProfile != "" && p.NetworkProfile != NetworkCustom {
+		return nil, fmt.Errorf("invalid networkprofile %q - valid options are 3g, slow-4g and custom", p.NetworkProfile)
+	}
+	if p.NetworkLatency < 0 || p.NetworkJitter < 0 || p.DownloadKbps < 0 || p.UploadKbps < 0 {
+		return nil, fmt.Errorf("networklatency, networkjitter, downloadkbps and uploadkbps must not be negative")
+	}
+	if p.DropRate < 0 || p.DropRate > 1 {
+		return nil, fmt.Errorf("droprate must be between 0 and 1")
+	}
+	if p.NetworkProfile == "" && p.NetworkLatency == 0 && p.NetworkJitter == 0 && p.DownloadKbps == 0 && p.UploadKbps == 0 && p.DropRate == 0 {
+		return nil, nil
+	}
+
+	conditions.Profile = p.NetworkProfile
+	if conditions.Profile == "" {
+		conditions.Profile = NetworkCustom
+	}
+	if p.NetworkLatency > 0 {
+		conditions.Latency = p.NetworkLatency
+	}
+	if p.NetworkJitter > 0 {
+		conditions.Jitter = p.NetworkJitter
+	}
+	if p.DownloadKbps > 0 {
+		conditions.DownloadKbps = p.DownloadKbps
+	}
+	if p.UploadKbps > 0 {
+		conditions.UploadKbps = p.UploadKbps
+	}
+	if p.DropRate > 0 {
+		conditions.DropRate = p.DropRate
+	}
+	return &conditions, nil
+}
+
+func (n NetworkConditions) String() string {
+	return fmt.Sprintf("%s - %s latency (±%s), %s down, %s up, %.1f%% drop rate",
+		n.Profile, n.Latency, n.Jitter, formatKbps(n.DownloadKbps), formatKbps(n.UploadKbps), n.DropRate*100)
+}
+
+func formatKbps(kbps int) string {
+	if kbps == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d kbps", kbps)
+}
+
+// latency picks a round trip time, varied by the jitter
+func (n NetworkConditions) latency() time.Duration {
+	latency := n.Latency
+	if n.Jitter > 0 {
+		latency += time.Duration(rand.Int63n(int64(2*n.Jitter)+1)) - n.Jitter
+	}
+	if latency < 0 {
+		return 0
+	}
+	return latency
+}
+
+// emulate waits out a round trip after connecting, to emulate the TCP handshake, then wraps the connection.
+func (n NetworkConditions) emulate(ctx context.Context, conn net.Conn) net.Conn {
+	time.Sleep(n.latency())
+	if timing := responseTimings.TimingFromContext(ctx); timing != nil && !timing.ConnectDone.IsZero() {
+		timing.ConnectDone = time.Now()
+	}
+	return newEmulatedConn(conn, n)
+}
+
+// inFlight is how many reads or writes an emulatedConn holds back at once, in each direction. It plays the part
+// of the TCP window, and is large enough that the emulated latency doesn't limit throughput.
+const inFlight = 256
+
+// emulatedConn delays everything sent or received by half the latency, so a request and its response take one
+// round trip on top of the server's time, and paces reads and writes to the bandwidth limits.
+//
+// Every byte is held back by the same amount, so the latency adds to the time to first byte and the time to
+// send the request, while the upload and download transfer times only depend on the bandwidth.
+// Received data is read ahead in the background and handed back once its delay is up, and writes return as soon
+// as they have been paced, leaving a background writer to pass them on once their delay is up.
+type emulatedConn struct {
+	net.Conn
+	conditions NetworkConditions
+	start      sync.Once
+	received   chan delivery
+	sending    chan delivery
+	closed     chan struct{}
+	closeOnce  sync.Once
+	readMutex  sync.Mutex // guards unread and readErr
+	unread     []byte     // the rest of a delivery that didn't fit in the last read
+	readErr    error
+	writeErr   atomic.Value // the first error from the background writer, returned by the writes after it
+	dropped    int32        // once dropped, every read and write fails, rather than reporting a closed connection
+}
+
+// delivery is data on its way across the emulated network, which is due at the other end at a set time
+type delivery struct {
+	data []byte
+	due  time.Time
+	err  error
+}
+
+func newEmulatedConn(conn net.Conn, conditions NetworkConditions) *emulatedConn {
+	return &emulatedConn{
+		Conn:       conn,
+		conditions: conditions,
+		received:   make(chan delivery, inFlight),
+		sending:    make(chan delivery, inFlight),
+		closed:     make(chan struct{}),
+	}
+}
+
+func (e *emulatedConn) Read(p []byte) (int, error) {
+	e.start.Do(e.run)
+	e.readMutex.Lock()
+	defer e.readMutex.Unlock()
+	if len(e.unread) == 0 && e.readErr == nil {
+		select {
+		case next := <-e.received:
+			time.Sleep(time.Until(next.due))
+			e.unread, e.readErr = next.data, next.err
+		case <-e.closed:
+			e.readErr = net.ErrClosed
+		}
+	}
+	if atomic.LoadInt32(&e.dropped) == 1 {
+		return 0, errConnectionDropped
+	}
+	n := copy(p, e.unread)
+	e.unread = e.unread[n:]
+	if len(e.unread) > 0 {
+		return n, e.maybeDrop(nil)
+	}
+	return n, e.maybeDrop(e.readErr)
+}
+
+func (e *emulatedConn) Write(p []byte) (int, error) {
+	e.start.Do(e.run)
+	if err := e.maybeDrop(nil); err != nil {
+		return 0, err
+	}
+	if err, _ := e.writeErr.Load().(error); err != nil {
+		return 0, err
+	}
+	throttle(len(p), e.conditions.UploadKbps)
+	select {
+	case e.sending <- delivery{data: append([]byte{}, p...), due: time.Now().Add(e.conditions.latency() / 2)}:
+		return len(p), nil
+	case <-e.closed:
+		if atomic.LoadInt32(&e.dropped) == 1 {
+			return 0, errConnectionDropped
+		}
+		return 0, net.ErrClosed
+	}
+}
+
+// Close stops the background reader and writer along with the connection. Writes still waiting out their delay
+// are never sent.
+func (e *emulatedConn) Close() (err error) {
+	err = net.ErrClosed
+	e.closeOnce.Do(func() {
+		close(e.closed)
+		err = e.Conn.Close()
+	})
+	return
+}
+
+// run starts the background reader and writer, which last until the connection is closed
+func (e *emulatedConn) run() {
+	go e.readAhead()
+	go e.sendWrites()
+}
+
+// readAhead reads from the connection at the download rate, and queues what arrives to be handed back by Read
+// after half the latency
+func (e *emulatedConn) readAhead() {
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := e.Conn.Read(buffer)
+		throttle(n, e.conditions.DownloadKbps)
+		select {
+		case e.received <- delivery{data: append([]byte{}, buffer[:n]...), due: time.Now().Add(e.conditions.latency() / 2), err: err}:
+		case <-e.closed:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// sendWrites passes queued writes on to the connection, in order, once each one's delay is up
+func (e *emulatedConn) sendWrites() {
+	for {
+		select {
+		case next := <-e.sending:
+			time.Sleep(time.Until(next.due))
+			if _, err := e.Conn.Write(next.data); err != nil {
+				e.writeErr.Store(err)
+				return
+			}
+		case <-e.closed:
+			return
+		}
+	}
+}
+
+func (e *emulatedConn) maybeDrop(err error) error {
+	if atomic.LoadInt32(&e.dropped) == 1 {
+		return errConnectionDropped
+	}
+	if err != nil || e.conditions.DropRate == 0 || rand.Float64() >= e.conditions.DropRate {
+		return err
+	}
+	atomic.StoreInt32(&e.dropped, 1)
+	e.Close()
+	return errConnectionDropped
+}
+
+// throttle waits for as long as sending bytes would take at the given rate
+func throttle(bytes int, kbps int) {
+	if kbps > 0 && bytes > 0 {
+		time.Sleep(time.Duration(bytes) * 8 * time.Millisecond / time.Duration(kbps))
+	}
+}
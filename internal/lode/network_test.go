@@ -0,0 +1,174 @@
+package lode
+
+import (
+	"github.com/JamesBalazs/lode/internal/lode/mocks"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParams_NetworkConditions(t *testing.T) {
+	assert := assert.New(t)
+
+	conditions, err := Params{}.NetworkConditions()
+	assert.Nil(conditions)
+	assert.Nil(err)
+
+	conditions, err = Params{NetworkProfile: "3g", UploadKbps: 500}.NetworkConditions()
+	assert.Nil(err)
+	assert.Equal(&NetworkConditions{Profile: "3g", Latency: 300 * time.Millisecond, Jitter: 50 * time.Millisecond, DownloadKbps: 1600, UploadKbps: 500}, conditions)
+
+	conditions, err = Params{NetworkLatency: time.Second, DropRate: 0.1}.NetworkConditions()
+	assert.Nil(err)
+	assert.Equal(&NetworkConditions{Profile: NetworkCustom, Latency: time.Second, DropRate: 0.1}, conditions)
+	assert.Equal("custom - 1s latency (±0s), unlimited down, unlimited up, 10.0% drop rate", conditions.String())
+}
+
+func TestParams_NetworkConditions_Errors(t *testing.T) {
+	for _, test := range []struct {
+		params   Params
+		expected string
+	}{
+		{Params{NetworkProfile: "5g"}, `invalid networkprofile "5g" - valid options are 3g, slow-4g and custom`},
+		{Params{NetworkJitter: -time.Second}, "networklatency, networkjitter, downloadkbps and uploadkbps must not be negative"},
+		{Params{DropRate: 1.5}, "droprate must be between 0 and 1"},
+	} {
+		conditions, err := test.params.NetworkConditions()
+
+		assert.Nil(t, conditions)
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func TestNetworkConditions_Latency(t *testing.T) {
+	conditions := NetworkConditions{Latency: 100 * time.Millisecond, Jitter: 20 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		latency := conditions.latency()
+
+		assert.GreaterOrEqual(t, latency, 80*time.Millisecond)
+		assert.LessOrEqual(t, latency, 120*time.Millisecond)
+	}
+	for i := 0; i < 100; i++ {
+		assert.GreaterOrEqual(t, NetworkConditions{Latency: 10 * time.Millisecond, Jitter: time.Second}.latency(), time.Duration(0))
+	}
+}
+
+func TestEmulatedConn(t *testing.T) {
+	assert := assert.New(t)
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := newEmulatedConn(client, NetworkConditions{Latency: 100 * time.Millisecond, UploadKbps: 80})
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 1000)
+		n, _ := server.Read(buffer)
+		_, _ = server.Write(buffer[:n])
+	}()
+
+	start := time.Now()
+	_, err := conn.Write(make([]byte, 1000))
+	assert.Nil(err)
+	// 1000 bytes at 80 kbps takes 100ms, and the latency is left to the round trip
+	assert.GreaterOrEqual(time.Since(start), 100*time.Millisecond)
+	assert.Less(time.Since(start), 150*time.Millisecond)
+
+	start = time.Now()
+	n, err := conn.Read(make([]byte, 1000))
+	assert.Nil(err)
+	assert.Equal(1000, n)
+	assert.GreaterOrEqual(time.Since(start), 100*time.Millisecond)
+}
+
+func TestEmulatedConn_ReadsAhead(t *testing.T) {
+	assert := assert.New(t)
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := newEmulatedConn(client, NetworkConditions{Latency: 100 * time.Millisecond})
+	defer conn.Close()
+	go func() {
+		for i := 0; i < 5; i++ {
+			_, _ = server.Write([]byte("lode"))
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	buffer := make([]byte, 4)
+	for i := 0; i < 5; i++ {
+		_, err := conn.Read(buffer)
+		assert.Nil(err)
+	}
+	// every read is delayed by the same 50ms, rather than each one adding to it
+	assert.GreaterOrEqual(time.Since(start), 90*time.Millisecond)
+	assert.Less(time.Since(start), 150*time.Millisecond)
+}
+
+func TestEmulatedConn_Drops(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := newEmulatedConn(client, NetworkConditions{DropRate: 1})
+	defer conn.Close()
+
+	_, err := conn.Write([]byte("lode"))
+
+	assert.Equal(t, errConnectionDropped, err)
+}
+
+func TestLode_RunRecordsDroppedConnection(t *testing.T) {
+	assert := assert.New(t)
+	NewClient = defaultNewClient
+	Logger = new(mocks.Log)
+	server := httptest.NewServer(http.HandlerFunc(okHandler))
+	defer server.Close()
+	params := timeoutParams(server.URL)
+	params.DropRate = 1
+	lode := New(params)
+
+	lode.Run()
+
+	response := lode.ResponseTimings[0].Response
+	assert.Equal("Connection dropped", response.Status)
+	assert.Contains(response.Error, errConnectionDropped.Error())
+	assert.Equal(1, lode.ExitCode)
+	assert.Equal(NetworkCustom, lode.Params.NetworkProfile)
+}
+
+func TestNewClient_NetworkLatency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(okHandler))
+	defer server.Close()
+
+	start := time.Now()
+	timing, body := tracedGet(t, defaultNewClient(Params{NetworkLatency: 100 * time.Millisecond}), server.URL)
+
+	assert.Equal(t, "ok", body)
+	// one round trip to connect, and one for the request
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	assert.GreaterOrEqual(t, timing.ConnectDone.Sub(timing.ConnectStart), 100*time.Millisecond)
+}
+
+func TestNewClient_NetworkLatencyAddsToTimeToFirstByte(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for i := 0; i < 20; i++ {
+			_, _ = w.Write(make([]byte, 64*1024))
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	client := defaultNewClient(Params{NetworkLatency: 100 * time.Millisecond}).(*http.Client)
+	client.Transport.(*http.Transport).TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	timing, body := tracedGet(t, client, server.URL)
+	timing.Done = time.Now()
+
+	assert.Len(body, 20*64*1024)
+	assert.GreaterOrEqual(timing.ServerDuration(), 100*time.Millisecond)
+	// the body streams in behind the first byte, rather than waiting out the latency again
+	assert.Less(timing.ResponseTransferDuration(), 100*time.Millisecond)
+}
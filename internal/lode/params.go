@@ -1,26 +1,35 @@
 package lode
 
 import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/files/rundata"
+	"github.com/JamesBalazs/lode/internal/redact"
+	"github.com/JamesBalazs/lode/internal/report"
 	"strings"
 	"time"
 )
 
-type Params struct {
-	Url            string
-	Method         string
-	Body           string
-	File           string
-	OutFile        string
-	OutFormat      string
-	Freq           int
-	Concurrency    int
-	MaxRequests    int
-	Delay          time.Duration
-	Timeout        time.Duration
-	MaxTime        time.Duration
-	Headers        []string
-	FailFast       bool
-	IgnoreFailures bool
+// Params is recorded in run files as is, so its fields are defined once in rundata.Params
+type Params rundata.Params
+
+func (p Params) Redactor() (redact.Redactor, error) {
+	return redact.New(redact.Rules{
+		Headers:    p.RedactHeaders,
+		Fields:     p.RedactFields,
+		Patterns:   p.RedactPatterns,
+		NoDefaults: p.NoDefaultRedaction,
+	})
+}
+
+// TimingResolution is what timings are truncated to, ms unless µs (or us) is chosen for sub-millisecond services
+func (p Params) TimingResolution() (time.Duration, error) {
+	switch p.Resolution {
+	case "", "ms":
+		return time.Millisecond, nil
+	case "us", "µs":
+		return time.Microsecond, nil
+	}
+	return 0, fmt.Errorf("invalid resolution %q - valid options are ms and us", p.Resolution)
 }
 
 func (p Params) Validate() {
@@ -47,6 +56,108 @@ func (p Params) Validate() {
 	if len(p.OutFormat) != 0 && p.OutFormat != "yaml" && p.OutFormat != "json" {
 		errors = append(errors, "invalid outFormat - valid options are json and yaml")
 	}
+	if p.MaxBodySize < 0 {
+		errors = append(errors, "maxbodysize must not be negative")
+	}
+	if p.CaptureEvery < 0 {
+		errors = append(errors, "captureevery must not be negative")
+	}
+	if p.MaxIdleConns < 0 {
+		errors = append(errors, "maxidleconns must not be negative")
+	}
+	if p.MaxConnsPerHost < 0 {
+		errors = append(errors, "maxconnsperhost must not be negative")
+	}
+	if p.DialTimeout < 0 || p.TlsTimeout < 0 || p.HeaderTimeout < 0 || p.IdleBodyTimeout < 0 {
+		errors = append(errors, "dialtimeout, tlstimeout, headertimeout and idlebodytimeout must not be negative")
+	}
+	if p.RedirectPolicy != "" && p.RedirectPolicy != RedirectFollow && p.RedirectPolicy != RedirectNone && p.RedirectPolicy != RedirectSameHost {
+		errors = append(errors, "invalid redirectpolicy - valid options are follow, none and same-host")
+	}
+	if p.MaxRedirects != nil && *p.MaxRedirects < 0 {
+		errors = append(errors, "maxredirects must not be negative")
+	}
+	for _, percentile := range p.Percentiles {
+		if percentile <= 0 || percentile > 100 {
+			errors = append(errors, "percentiles must be greater than 0 and at most 100")
+			break
+		}
+	}
+	if _, err := p.TimingResolution(); err != nil {
+		errors = append(errors, err.Error())
+	}
+	if p.Warmup < 0 || p.WarmupRequests < 0 {
+		errors = append(errors, "warmup and warmuprequests must not be negative")
+	}
+	if p.Apdex < 0 {
+		errors = append(errors, "apdex must not be negative")
+	}
+	if p.MinApdex < 0 || p.MinApdex > 1 {
+		errors = append(errors, "minapdex must be between 0 and 1")
+	} else if p.MinApdex > 0 && p.Apdex == 0 {
+		errors = append(errors, "minapdex requires apdex")
+	}
+	if p.Slo != "" {
+		if _, err := report.ParseSlo(p.Slo); err != nil {
+			errors = append(errors, err.Error())
+		}
+	} else if p.FailOnSlo {
+		errors = append(errors, "failonslo requires slo")
+	}
+	if p.ConvergePercentile < 0 || p.ConvergePercentile > 100 {
+		errors = append(errors, "convergepercentile must be greater than 0 and at most 100")
+	} else if p.ConvergePercentile > 0 && p.ConvergeWidth == 0 {
+		errors = append(errors, "convergepercentile requires convergewidth")
+	}
+	if p.ConvergeWidth < 0 {
+		errors = append(errors, "convergewidth must not be negative")
+	}
+	if p.AdaptiveLatency < 0 {
+		errors = append(errors, "adaptivelatency must not be negative")
+	} else if p.AdaptiveLatency > 0 && !p.Adaptive {
+		errors = append(errors, "adaptivelatency requires adaptive")
+	}
+	if p.GroupBy != "" {
+		if _, err := report.NewGrouper(p.GroupBy); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	if p.ForceHttp1 && (p.ForceHttp2 || p.H2cPriorKnowledge) {
+		errors = append(errors, "forcehttp1 cannot be combined with forcehttp2 or h2cpriorknowledge")
+	}
+	// unix:// urls are sent as http://, so check the url requests are actually sent to
+	target, unixSocket, _ := p.Target()
+	if p.ForceHttp2 && !strings.HasPrefix(target, "https://") {
+		errors = append(errors, "forcehttp2 requires an https url - use h2cpriorknowledge for cleartext HTTP/2")
+	}
+	if p.H2cPriorKnowledge && !strings.HasPrefix(target, "http://") {
+		errors = append(errors, "h2cpriorknowledge requires an http url")
+	}
+	if p.ForceHttp2 && len(p.TlsAlpn) > 0 && !containsString(p.TlsAlpn, "h2") {
+		errors = append(errors, "tlsalpn must include h2 when forcehttp2 is set")
+	}
+	if _, err := p.Redactor(); err != nil {
+		errors = append(errors, err.Error())
+	}
+	if p.PreferIpv4 && p.PreferIpv6 {
+		errors = append(errors, "preferipv4 cannot be combined with preferipv6")
+	}
+	if _, err := p.ProxyUrl(); err != nil {
+		errors = append(errors, err.Error())
+	} else if p.Proxy != "" && p.H2cPriorKnowledge {
+		errors = append(errors, "proxy cannot be combined with h2cpriorknowledge")
+	} else if p.Proxy != "" && unixSocket != "" {
+		errors = append(errors, "proxy cannot be combined with a unix socket")
+	}
+	if len(p.SourceAddrs) > 0 && unixSocket != "" {
+		errors = append(errors, "sourceaddrs cannot be combined with a unix socket")
+	}
+	if _, err := p.Dialer(); err != nil {
+		errors = append(errors, err.Error())
+	}
+	if _, err := p.TlsConfig(); err != nil {
+		errors = append(errors, err.Error())
+	}
 	if len(errors) != 0 {
 		Logger.Panicf("Invalid test suite:\n%s\n", strings.Join(errors, "\n"))
 	}
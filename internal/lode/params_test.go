@@ -2,6 +2,7 @@ package lode
 
 import (
 	"github.com/JamesBalazs/lode/internal/lode/mocks"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
 	"time"
@@ -92,4 +93,207 @@ func TestParams_Validate(t *testing.T) {
 	param.Validate()
 	logMock.AssertExpectations(t)
 	param.OutFile, param.OutFormat = oldParam.OutFile, oldParam.OutFormat
+
+	param.MaxBodySize = -1
+	logMock.On("Panicf", invalidSuite, "maxbodysize must not be negative").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.MaxBodySize = oldParam.MaxBodySize
+
+	param.CaptureEvery = -1
+	logMock.On("Panicf", invalidSuite, "captureevery must not be negative").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.CaptureEvery = oldParam.CaptureEvery
+
+	param.RedactFields = []string{"$.items[abc]"}
+	logMock.On("Panicf", invalidSuite, `invalid redaction field "$.items[abc]": bad index [abc]`).Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.RedactFields = oldParam.RedactFields
+
+	param.MaxIdleConns = -1
+	logMock.On("Panicf", invalidSuite, "maxidleconns must not be negative").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.MaxIdleConns = oldParam.MaxIdleConns
+
+	param.MaxConnsPerHost = -1
+	logMock.On("Panicf", invalidSuite, "maxconnsperhost must not be negative").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.MaxConnsPerHost = oldParam.MaxConnsPerHost
+
+	param.ForceHttp1, param.ForceHttp2 = true, true
+	logMock.On("Panicf", invalidSuite, "forcehttp1 cannot be combined with forcehttp2 or h2cpriorknowledge").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.ForceHttp1, param.ForceHttp2 = oldParam.ForceHttp1, oldParam.ForceHttp2
+
+	param.Url, param.ForceHttp2 = "http://www.google.com", true
+	logMock.On("Panicf", invalidSuite, "forcehttp2 requires an https url - use h2cpriorknowledge for cleartext HTTP/2").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.Url, param.ForceHttp2 = oldParam.Url, oldParam.ForceHttp2
+
+	param.H2cPriorKnowledge = true
+	logMock.On("Panicf", invalidSuite, "h2cpriorknowledge requires an http url").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.H2cPriorKnowledge = oldParam.H2cPriorKnowledge
+
+	param.TlsCert = "client.crt"
+	logMock.On("Panicf", invalidSuite, "tlscert and tlskey must be provided together").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.TlsCert = oldParam.TlsCert
+
+	param.ForceHttp2, param.TlsAlpn = true, []string{"http/1.1"}
+	logMock.On("Panicf", invalidSuite, "tlsalpn must include h2 when forcehttp2 is set").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.ForceHttp2, param.TlsAlpn = oldParam.ForceHttp2, oldParam.TlsAlpn
+
+	param.HeaderTimeout = -time.Second
+	logMock.On("Panicf", invalidSuite, "dialtimeout, tlstimeout, headertimeout and idlebodytimeout must not be negative").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.HeaderTimeout = oldParam.HeaderTimeout
+
+	param.PreferIpv4, param.PreferIpv6 = true, true
+	logMock.On("Panicf", invalidSuite, "preferipv4 cannot be combined with preferipv6").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.PreferIpv4, param.PreferIpv6 = oldParam.PreferIpv4, oldParam.PreferIpv6
+
+	param.Resolve = []string{"example.com:443"}
+	logMock.On("Panicf", invalidSuite, `invalid resolve "example.com:443" - expected host:port:addr, where addr is an IP address`).Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.Resolve = oldParam.Resolve
+
+	param.Proxy = "ftp://proxy"
+	logMock.On("Panicf", invalidSuite, `invalid proxy "ftp://proxy" - expected a URL with an http, https, socks5 or socks5h scheme`).Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.Proxy = oldParam.Proxy
+
+	param.Url, param.Proxy, param.H2cPriorKnowledge = "http://www.google.com", "http://proxy:3128", true
+	logMock.On("Panicf", invalidSuite, "proxy cannot be combined with h2cpriorknowledge").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.Url, param.Proxy, param.H2cPriorKnowledge = oldParam.Url, oldParam.Proxy, oldParam.H2cPriorKnowledge
+
+	param.Proxy, param.UnixSocket = "http://proxy:3128", "/var/run/app.sock"
+	logMock.On("Panicf", invalidSuite, "proxy cannot be combined with a unix socket").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.Proxy, param.UnixSocket = oldParam.Proxy, oldParam.UnixSocket
+
+	param.SourceAddrs, param.UnixSocket = []string{"127.0.0.1"}, "/var/run/app.sock"
+	logMock.On("Panicf", invalidSuite, "sourceaddrs cannot be combined with a unix socket").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.SourceAddrs, param.UnixSocket = oldParam.SourceAddrs, oldParam.UnixSocket
+
+	param.SourceAddrs = []string{"not-an-interface"}
+	logMock.On("Panicf", invalidSuite, `invalid sourceaddr "not-an-interface" - expected an IP address or interface name`).Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.SourceAddrs = oldParam.SourceAddrs
+
+	param.RedirectPolicy = "always"
+	logMock.On("Panicf", invalidSuite, "invalid redirectpolicy - valid options are follow, none and same-host").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.RedirectPolicy = oldParam.RedirectPolicy
+
+	negative := -1
+	param.MaxRedirects = &negative
+	logMock.On("Panicf", invalidSuite, "maxredirects must not be negative").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.MaxRedirects = oldParam.MaxRedirects
+
+	param.NetworkProfile = "5g"
+	logMock.On("Panicf", invalidSuite, `invalid networkprofile "5g" - valid options are 3g, slow-4g and custom`).Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.NetworkProfile = oldParam.NetworkProfile
+
+	param.Percentiles = []float64{50, 100.1}
+	logMock.On("Panicf", invalidSuite, "percentiles must be greater than 0 and at most 100").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.Percentiles = oldParam.Percentiles
+
+	param.Resolution = "ns"
+	logMock.On("Panicf", invalidSuite, `invalid resolution "ns" - valid options are ms and us`).Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.Resolution = oldParam.Resolution
+
+	param.GroupBy = "header"
+	logMock.On("Panicf", invalidSuite, `invalid groupby "header" - valid options are header:<name>, path and status`).Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.GroupBy = oldParam.GroupBy
+
+	param.WarmupRequests = -1
+	logMock.On("Panicf", invalidSuite, "warmup and warmuprequests must not be negative").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.WarmupRequests = oldParam.WarmupRequests
+
+	param.MinApdex = 0.9
+	logMock.On("Panicf", invalidSuite, "minapdex requires apdex").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.MinApdex = oldParam.MinApdex
+
+	param.Slo = "fast"
+	logMock.On("Panicf", invalidSuite, `invalid slo "fast" - expected a target and latency, e.g. 99.5% under 400ms`).Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.Slo = oldParam.Slo
+
+	param.FailOnSlo = true
+	logMock.On("Panicf", invalidSuite, "failonslo requires slo").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.FailOnSlo = oldParam.FailOnSlo
+
+	param.ConvergePercentile = 99
+	logMock.On("Panicf", invalidSuite, "convergepercentile requires convergewidth").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.ConvergePercentile = oldParam.ConvergePercentile
+
+	param.ConvergeWidth = -time.Millisecond
+	logMock.On("Panicf", invalidSuite, "convergewidth must not be negative").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.ConvergeWidth = oldParam.ConvergeWidth
+
+	param.AdaptiveLatency = 200 * time.Millisecond
+	logMock.On("Panicf", invalidSuite, "adaptivelatency requires adaptive").Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.AdaptiveLatency = oldParam.AdaptiveLatency
+
+	param.Url = "unix:///var/run/missing.sock/health"
+	logMock.On("Panicf", invalidSuite, `no unix socket found in url "unix:///var/run/missing.sock/health"`).Return().Once()
+	param.Validate()
+	logMock.AssertExpectations(t)
+	param.Url = oldParam.Url
+}
+
+func TestParams_TimingResolution(t *testing.T) {
+	assert := assert.New(t)
+	for resolution, expected := range map[string]time.Duration{"": time.Millisecond, "ms": time.Millisecond, "us": time.Microsecond, "µs": time.Microsecond} {
+		actual, err := Params{Resolution: resolution}.TimingResolution()
+
+		assert.Nil(err)
+		assert.Equal(expected, actual)
+	}
 }
@@ -0,0 +1,98 @@
+package lode
+
+import (
+	"context"
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var proxySchemes = map[string]bool{"http": true, "https": true, "socks5": true, "socks5h": true}
+
+// ProxyUrl parses the proxy param, returning nil if no proxy was given.
+func (p Params) ProxyUrl() (*url.URL, error) {
+	if p.Proxy == "" {
+		return nil, nil
+	}
+	proxyUrl, err := url.Parse(p.Proxy)
+	if err != nil || !proxySchemes[proxyUrl.Scheme] || proxyUrl.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q - expected a URL with an http, https, socks5 or socks5h scheme", p.Proxy)
+	}
+	return proxyUrl, nil
+}
+
+// proxyFunc picks the proxy for a target URL, skipping hosts matched by noProxy, or the NO_PROXY
+// environment variable if noProxy is empty. Without a proxy param, the environment is used as is.
+func proxyFunc(proxyUrl *url.URL, noProxy string) func(*url.URL) (*url.URL, error) {
+	config := httpproxy.FromEnvironment()
+	if proxyUrl != nil {
+		config.HTTPProxy, config.HTTPSProxy = proxyUrl.String(), proxyUrl.String()
+	}
+	if noProxy != "" {
+		config.NoProxy = noProxy
+	}
+	return config.ProxyFunc()
+}
+
+// setProxy routes the transport through the proxy, recording which proxy was used and when the
+// CONNECT tunnel was requested. SOCKS proxies are dialed here rather than by the transport, so the
+// handshake can be timed.
+func setProxy(transport *http.Transport, dialer *Dialer, proxyUrl *url.URL, noProxy string) {
+	forTarget := proxyFunc(proxyUrl, noProxy)
+
+	if proxyUrl != nil && (proxyUrl.Scheme == "socks5" || proxyUrl.Scheme == "socks5h") {
+		socksDialer, _ := proxy.FromURL(proxyUrl, tunnelTimingDialer{dialer})
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if chosen, err := forTarget(&url.URL{Scheme: "https", Host: addr}); err != nil || chosen == nil {
+				return dialer.DialContext(ctx, network, addr)
+			}
+			timing := responseTimings.TimingFromContext(ctx)
+			if timing != nil {
+				timing.Connection.Proxy = proxyUrl.Redacted()
+			}
+			conn, err := socksDialer.(proxy.ContextDialer).DialContext(ctx, network, addr)
+			if timing != nil && err == nil {
+				timing.TunnelDone = time.Now()
+			}
+			return conn, err
+		}
+		return
+	}
+
+	transport.Proxy = func(request *http.Request) (*url.URL, error) {
+		chosen, err := forTarget(request.URL)
+		if timing := responseTimings.TimingFromContext(request.Context()); timing != nil && chosen != nil {
+			timing.Connection.Proxy = chosen.Redacted()
+		}
+		return chosen, err
+	}
+	transport.GetProxyConnectHeader = func(ctx context.Context, _ *url.URL, _ string) (http.Header, error) {
+		if timing := responseTimings.TimingFromContext(ctx); timing != nil {
+			timing.TunnelStart = time.Now()
+		}
+		return nil, nil
+	}
+}
+
+// tunnelTimingDialer connects to a SOCKS proxy, marking the start of the tunnel once connected
+type tunnelTimingDialer struct {
+	dialer *Dialer
+}
+
+func (t tunnelTimingDialer) Dial(network, addr string) (net.Conn, error) {
+	return t.DialContext(context.Background(), network, addr)
+}
+
+func (t tunnelTimingDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := t.dialer.DialContext(ctx, network, addr)
+	if timing := responseTimings.TimingFromContext(ctx); timing != nil && err == nil {
+		timing.TunnelStart = time.Now()
+	}
+	return conn, err
+}
@@ -0,0 +1,36 @@
+package lode
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"net/http"
+	"strings"
+)
+
+const (
+	RedirectFollow   = "follow"
+	RedirectNone     = "none"
+	RedirectSameHost = "same-host"
+
+	// DefaultMaxRedirects is followed when no limit is given
+	DefaultMaxRedirects = 10
+)
+
+// checkRedirect applies the redirect policy, recording the timing of each hop that is followed. When a
+// redirect isn't followed, the redirect response itself is recorded rather than failing the request.
+func (p Params) checkRedirect(request *http.Request, via []*http.Request) error {
+	maxRedirects := DefaultMaxRedirects
+	if p.MaxRedirects != nil {
+		maxRedirects = *p.MaxRedirects
+	}
+	previous := via[len(via)-1]
+	if p.RedirectPolicy == RedirectNone || len(via) > maxRedirects ||
+		(p.RedirectPolicy == RedirectSameHost && !strings.EqualFold(request.URL.Hostname(), previous.URL.Hostname())) {
+		return http.ErrUseLastResponse
+	}
+
+	if timing := responseTimings.TimingFromContext(request.Context()); timing != nil {
+		timing.Connection.Protocol = request.Response.Proto
+		timing.Redirected(previous.URL.String(), request.Response.StatusCode)
+	}
+	return nil
+}
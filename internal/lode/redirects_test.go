@@ -0,0 +1,73 @@
+package lode
+
+import (
+	"context"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// startRedirectServer redirects /a to /b, /b to /c on localhost, and answers /c
+func startRedirectServer(t *testing.T) string {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			http.Redirect(w, r, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)+"/c", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestNewClient_RedirectFollow(t *testing.T) {
+	assert := assert.New(t)
+	url := startRedirectServer(t)
+
+	timing, body := tracedGet(t, defaultNewClient(Params{}), url+"/a")
+
+	assert.Equal("ok", body)
+	if assert.Len(timing.Redirects, 2) {
+		assert.Equal(url+"/a", timing.Redirects[0].Url)
+		assert.Equal(301, timing.Redirects[0].StatusCode)
+		assert.Equal("HTTP/1.1", timing.Redirects[0].Timing.Connection.Protocol)
+		assert.Equal(url+"/b", timing.Redirects[1].Url)
+		assert.Equal(302, timing.Redirects[1].StatusCode)
+	}
+	assert.False(timing.GotConn.Before(timing.Redirects[1].Timing.Done))
+	assert.False(timing.Redirects[0].Timing.GotConn.IsZero())
+}
+
+func TestNewClient_RedirectPolicies(t *testing.T) {
+	url := startRedirectServer(t)
+
+	zero, one, two := 0, 1, 2
+	for _, test := range []struct {
+		params    Params
+		status    int
+		redirects int
+	}{
+		{Params{RedirectPolicy: RedirectNone}, 301, 0},
+		{Params{RedirectPolicy: RedirectSameHost}, 302, 1},
+		{Params{RedirectPolicy: RedirectFollow, MaxRedirects: &zero}, 301, 0},
+		{Params{RedirectPolicy: RedirectFollow, MaxRedirects: &one}, 302, 1},
+		{Params{RedirectPolicy: RedirectFollow, MaxRedirects: &two}, 200, 2},
+		{Params{RedirectPolicy: RedirectFollow}, 200, 2},
+	} {
+		timing := &responseTimings.Timing{}
+		request, _ := http.NewRequestWithContext(responseTimings.WithTiming(context.Background(), timing), "GET", url+"/a", nil)
+		response, err := defaultNewClient(test.params).Do(request)
+
+		assert.Nil(t, err)
+		response.Body.Close()
+		assert.Equal(t, test.status, response.StatusCode)
+		assert.Len(t, timing.Redirects, test.redirects)
+	}
+}
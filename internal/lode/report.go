@@ -1,6 +1,8 @@
 package lode
 
 import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/files/rundata"
 	"github.com/JamesBalazs/lode/internal/report"
 	"github.com/JamesBalazs/lode/internal/responseTimings"
 	"github.com/JamesBalazs/lode/internal/types"
@@ -13,21 +15,49 @@ import (
 
 var reportTemplate = &promptui.SelectTemplates{
 	Label:    "{{ . }}?",
-	Active:   "\U0000276F {{ .Response.Status | cyan }} (Duration {{ .Timing.TotalDuration | red }})",
-	Inactive: "  {{ .Response.Status | cyan }} (Duration {{ .Timing.TotalDuration | red }})",
+	Active:   "\U0000276F {{ .Response.Status | cyan }} (Duration {{ .Timing.TotalDuration | red }}){{ if .Warmup }} warm-up{{ end }}",
+	Inactive: "  {{ .Response.Status | cyan }} (Duration {{ .Timing.TotalDuration | red }}){{ if .Warmup }} warm-up{{ end }}",
 	Details: `
+{{- with .Request }}
 Request details:
+{{ "Method:" | faint }}	{{ .Method }}
+{{ "URL:" | faint }}	{{ .Url }}
+{{ "Worker:" | faint }}	{{ .WorkerId }} (attempt {{ .Attempt }})
+{{- end }}
+{{- if not .Timing.Start.IsZero }}
+{{ "Started:" | faint }}	{{ .Timing.Start.Format "2006-01-02T15:04:05.000Z07:00" }}
+{{- end }}
+{{- with .Request }}
+
+Request headers:
+{{ .Header }}
+Request body ({{ .BodySize }} bytes{{ if .BodyTruncated }}, truncated{{ end }}):
+{{ .Body }}
+{{ end }}
+Response details:
 {{ "Status:" | faint }}	{{ .Response.Status }}
 {{ "Code:" | faint }}	{{ .Response.StatusCode }}
+{{ "Received:" | faint }}	{{ .Response.WireSize }} bytes ({{ .Response.DecodedSize }} decompressed)
+{{- with .Response.Error }}
+{{ "Error:" | faint }}	{{ . }}
+{{- end }}
 {{ "Timing breakdown:" | faint }}
 {{ .Timing.String }}
+{{ "Connection details:" | faint }}
+{{ .Timing.Connection }}
 
-Request headers:
+Response headers:
 {{ .Response.Header }}
-Request body:
+Response body ({{ .Response.BodySize }} bytes{{ if .Response.BodyTruncated }}, truncated{{ end }}):
 {{ .Response.Body }}`,
 }
 
+// heatmapColumns is how many slices of the run the latency heatmap is split into
+const heatmapColumns = 60
+
+// concurrencyPeriods is how many slices of the run the adaptive concurrency timeline is split into
+const concurrencyPeriods = 10
+
 var newInteractivePrompt = func(label string, responseTimings responseTimings.ResponseTimings) types.PromptSelectInt {
 	return &promptui.Select{
 		Label:     label,
@@ -46,35 +76,151 @@ type TestReport struct {
 	Duration        time.Duration
 	ResponseCount   int
 	RequestRate     float64
+	WarmupCount     int  // left out of the statistics, but still in ResponseTimings
+	Converged       bool // stopped early, once the convergence percentile was precise enough
+	Concurrencies   []report.ConcurrencySample
 	ResponseTimings responseTimings.ResponseTimings
+	Params          Params
+	StartTime       time.Time
+	FinishTime      time.Time
 	Interactive     bool
 }
 
 func NewTestReport(lode *Lode) TestReport {
-	duration := lode.FinishTime.Sub(lode.StartTime).Truncate(responseTimings.TimingResolution)
-	responseCount := len(lode.ResponseTimings)
+	// statistics only cover the measured requests, after any warm-up
+	measureStart := lode.StartTime
+	if !lode.MeasureStart.IsZero() {
+		measureStart = lode.MeasureStart
+	}
+	resolution, _ := lode.Params.TimingResolution()
+	duration := lode.FinishTime.Sub(measureStart).Truncate(resolution)
+	responseCount := len(lode.ResponseTimings.Measured())
+
+	target := lode.Request.URL.String()
+	if strings.HasPrefix(lode.Params.Url, unixScheme) {
+		target = lode.Params.Url
+	}
 
 	return TestReport{
-		Target:          strings.Join([]string{lode.Request.Method, lode.Request.URL.String()}, " "),
+		Target:          strings.Join([]string{lode.Request.Method, target}, " "),
 		Concurrency:     lode.Concurrency,
 		Duration:        duration,
 		ResponseCount:   responseCount,
 		RequestRate:     math.Round((float64(responseCount)/duration.Seconds())*100) / 100,
+		WarmupCount:     len(lode.ResponseTimings) - responseCount,
+		Converged:       lode.Converged,
+		Concurrencies:   lode.Concurrencies,
 		ResponseTimings: lode.ResponseTimings,
+		Params:          lode.Params,
+		StartTime:       lode.StartTime,
+		FinishTime:      lode.FinishTime,
 		Interactive:     lode.Interactive,
 	}
 }
 
+// resolution is what the report's timings were truncated to, from the params of the run they came from
+func (t TestReport) resolution() time.Duration {
+	resolution, _ := t.Params.TimingResolution()
+	return resolution
+}
+
 func (t TestReport) StatusHistogram() report.StatusHistogram {
-	return report.BuildStatusHistogram(t.ResponseTimings.Responses(), t.ResponseCount)
+	return report.BuildStatusHistogram(t.ResponseTimings.Measured().Responses(), t.ResponseCount)
 }
 
 func (t TestReport) LatencyPercentiles() report.LatencyPercentiles {
-	return report.BuildLatencyPercentiles(t.ResponseTimings.Timings())
+	return report.BuildLatencyPercentiles(t.ResponseTimings.Measured().Timings(), t.Params.Percentiles, t.resolution())
+}
+
+// ShowConfidenceIntervals is true when they were asked for, or the run watched one to converge, as bootstrapping them is slow
+func (t TestReport) ShowConfidenceIntervals() bool {
+	return t.Params.ConfidenceIntervals || t.Params.ConvergeWidth > 0
+}
+
+func (t TestReport) ConfidenceIntervals() report.ConfidenceIntervals {
+	return report.BuildConfidenceIntervals(t.ResponseTimings.Measured().Timings(), t.Params.Percentiles, t.resolution())
+}
+
+// ConvergedPercentile is the percentile a converged run watched, e.g. 99th
+func (t TestReport) ConvergedPercentile() string {
+	if convergence := t.Params.convergence(); convergence != nil {
+		return report.FormatPercentile(convergence.percentile)
+	}
+	return ""
+}
+
+func (t TestReport) LatencyDistribution() report.LatencyDistribution {
+	return report.BuildLatencyDistribution(t.ResponseTimings.Measured().Timings(), t.resolution())
+}
+
+func (t TestReport) LatencyHeatmap() report.LatencyHeatmap {
+	return report.BuildLatencyHeatmap(t.ResponseTimings.Measured().Timings(), heatmapColumns, t.resolution())
+}
+
+// ConcurrencyTimeline covers the whole run, including any warm-up, as the limit carries on from it
+func (t TestReport) ConcurrencyTimeline() report.ConcurrencyTimeline {
+	return report.BuildConcurrencyTimeline(t.Concurrencies, t.FinishTime.Sub(t.StartTime), t.Concurrency, concurrencyPeriods)
+}
+
+func (t TestReport) PhasePercentiles() report.PhasePercentiles {
+	return report.BuildPhasePercentiles(t.ResponseTimings.Measured().Timings(), t.resolution())
+}
+
+func (t TestReport) ConnectionSummary() report.ConnectionSummary {
+	return report.BuildConnectionSummary(t.ResponseTimings.Measured().Timings())
+}
+
+func (t TestReport) TimeoutBreakdown() report.TimeoutBreakdown {
+	return report.BuildTimeoutBreakdown(t.ResponseTimings.Measured().Responses())
+}
+
+func (t TestReport) BodySummary() report.BodySummary {
+	return report.BuildBodySummary(t.ResponseTimings.Measured(), t.Duration, t.resolution())
+}
+
+func (t TestReport) RedirectSummary() report.RedirectSummary {
+	return report.BuildRedirectSummary(t.ResponseTimings.Measured().Timings(), t.resolution())
+}
+
+func (t TestReport) RedirectWaterfall() report.RedirectWaterfall {
+	return report.BuildRedirectWaterfall(t.FirstResponse())
+}
+
+func (t TestReport) SourceLatencies() report.SourceLatencies {
+	return report.BuildSourceLatencies(t.ResponseTimings.Measured().Timings(), t.resolution())
+}
+
+func (t TestReport) Groups() report.Groups {
+	grouper, _ := report.NewGrouper(t.Params.GroupBy)
+	return report.BuildGroups(grouper, t.ResponseTimings.Measured(), t.Params.Percentiles, t.resolution())
+}
+
+func (t TestReport) Apdex() report.Apdex {
+	return report.BuildApdex(t.ResponseTimings.Measured(), t.Params.Apdex)
+}
+
+func (t TestReport) SloCompliance() report.SloCompliance {
+	slo, _ := report.ParseSlo(t.Params.Slo)
+	return report.BuildSloCompliance(t.ResponseTimings.Measured(), slo)
+}
+
+// ThresholdsMissed gives the reasons the run should fail, from the minimum Apdex score and SLO
+func (t TestReport) ThresholdsMissed() (missed []string) {
+	if t.Params.MinApdex > 0 {
+		if apdex := t.Apdex(); apdex.Score() < t.Params.MinApdex {
+			missed = append(missed, fmt.Sprintf("Apdex %.2f is below the minimum of %.2f", apdex.Score(), t.Params.MinApdex))
+		}
+	}
+	if t.Params.FailOnSlo {
+		if compliance := t.SloCompliance(); !compliance.Met() {
+			missed = append(missed, fmt.Sprintf("SLO %s was missed, with %.1f%% of the error budget consumed", compliance.Slo, compliance.BudgetConsumed()))
+		}
+	}
+	return
 }
 
 func (t TestReport) FirstResponse() responseTimings.ResponseTiming {
-	return t.ResponseTimings[0]
+	return t.ResponseTimings.Measured()[0]
 }
 
 func (t TestReport) MultipleResponses() bool {
@@ -87,18 +233,59 @@ func (t TestReport) OneResponse() bool {
 
 func (t TestReport) Output() string {
 	templateString := `Target: {{ .Target }}
-Concurrency: {{ .Concurrency }}
+Concurrency: {{ if .Params.Adaptive }}adaptive, up to {{ end }}{{ .Concurrency }}
 Requests made: {{ .ResponseCount }}
 Time taken: {{ .Duration }}
 Requests per second (avg): {{ .RequestRate }}
+{{- with .WarmupCount }}
+Warm-up requests excluded: {{ . }}
+{{- end }}
+{{- if .Converged }}
+Stopped early: the {{ .ConvergedPercentile }} percentile's confidence interval was within {{ .Params.ConvergeWidth }}
+{{- end }}
+{{- with .Params.NetworkConditions }}
+Network emulation: {{ . }}
+{{- end }}
 {{ if or .MultipleResponses .Interactive }}
 Response code breakdown:
 {{ .StatusHistogram }}
-Percentile latency breakdown:
+{{ with .TimeoutBreakdown }}{{ if .TimeoutCount }}Timeout breakdown:
+{{ . }}
+{{ end }}{{ end }}{{ if .Params.Apdex }}{{ .Apdex }}{{ end }}{{ if .Params.Slo }}{{ .SloCompliance }}{{ end }}{{ range .ThresholdsMissed }}Threshold missed: {{ . }}
+{{ end }}{{ if or .Params.Apdex .Params.Slo }}
+{{ end }}Percentile latency breakdown:
 {{ .LatencyPercentiles }}
+{{ if .ShowConfidenceIntervals }}Confidence intervals (95%):
+{{ .ConfidenceIntervals }}
+{{ end }}Latency distribution:
+{{ .LatencyDistribution }}
+{{ if .Params.Heatmap }}Latency over time:
+{{ .LatencyHeatmap }}
+{{ end }}{{ if .Concurrencies }}Concurrency over time:
+{{ .ConcurrencyTimeline }}
+{{ end }}Phase latency breakdown:
+{{ .PhasePercentiles }}
+{{ with .RedirectSummary }}{{ if .RedirectedCount }}Redirect breakdown:
+{{ . }}
+{{ end }}{{ end }}{{ with .SourceLatencies }}{{ if gt (len .Counts) 1 }}Latency by source address:
+{{ . }}
+{{ end }}{{ end }}{{ if .Params.GroupBy }}Breakdown by {{ .Params.GroupBy }}:
+{{ .Groups }}
+{{ end }}Body breakdown:
+{{ .BodySummary }}
+Connection breakdown:
+{{ .ConnectionSummary }}
 {{ else if .OneResponse }}
 Timing breakdown:
 {{ .FirstResponse.Timing }}
+{{ with .RedirectWaterfall }}{{ if .Hops }}
+Redirect waterfall:
+{{ . }}{{ end }}{{ end }}{{ with .FirstResponse.Response.Error }}
+Error: {{ . }}
+{{ end }}{{ with .FirstResponse.Timing }}
+Connection details:
+{{ .Connection }}
+{{ end }}
 {{ else }}
 No requests made...
 {{ end }}`
@@ -119,14 +306,18 @@ No requests made...
 	return builder.String()
 }
 
-func (t TestReport) ToRunData() RunDataV1 {
-	return RunDataV1{
-		Version:         "1",
-		Target:          t.Target,
-		Concurrency:     t.Concurrency,
+func (t TestReport) ToRunData() rundata.RunDataV2 {
+	return rundata.RunDataV2{
+		Version:         rundata.VersionV2,
+		LodeVersion:     Version,
+		Params:          rundata.Params(t.Params),
+		Environment:     rundata.CurrentEnvironment(),
+		StartTime:       t.StartTime,
+		FinishTime:      t.FinishTime,
 		Duration:        t.Duration,
 		ResponseCount:   t.ResponseCount,
 		RequestRate:     t.RequestRate,
 		ResponseTimings: t.ResponseTimings,
+		Concurrencies:   t.Concurrencies,
 	}
 }
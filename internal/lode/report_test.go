@@ -2,7 +2,9 @@ package lode
 
 import (
 	"errors"
+	"github.com/JamesBalazs/lode/internal/files/rundata"
 	"github.com/JamesBalazs/lode/internal/lode/mocks"
+	"github.com/JamesBalazs/lode/internal/report"
 	"github.com/JamesBalazs/lode/internal/responseTimings"
 	"github.com/JamesBalazs/lode/internal/types"
 	"github.com/stretchr/testify/assert"
@@ -51,6 +53,7 @@ func TestNewTestReport(t *testing.T) {
 		ResponseCount:   2,
 		RequestRate:     0.2,
 		ResponseTimings: responseTimings,
+		FinishTime:      time.Time{}.Add(10 * time.Second),
 	}
 
 	tr := NewTestReport(lode)
@@ -58,6 +61,30 @@ func TestNewTestReport(t *testing.T) {
 	assert.Equal(t, expectedReport, tr)
 }
 
+func TestNewTestReport_Warmup(t *testing.T) {
+	assert := assert.New(t)
+	request, _ := http.NewRequest(params.Method, params.Url, nil)
+	warmup := responseTiming
+	warmup.Warmup = true
+	lode := &Lode{
+		Request:         request,
+		StartTime:       time.Time{},
+		MeasureStart:    time.Time{}.Add(2 * time.Second),
+		FinishTime:      time.Time{}.Add(10 * time.Second),
+		ResponseTimings: responseTimings.ResponseTimings{warmup, warmup, responseTiming, responseTiming},
+	}
+
+	tr := NewTestReport(lode)
+
+	assert.Equal(8*time.Second, tr.Duration)
+	assert.Equal(2, tr.ResponseCount)
+	assert.Equal(0.25, tr.RequestRate)
+	assert.Equal(2, tr.WarmupCount)
+	assert.Len(tr.ResponseTimings, 4)
+	assert.Equal(2, tr.StatusHistogram().TotalCount)
+	assert.Contains(tr.Output(), "Requests per second (avg): 0.25\nWarm-up requests excluded: 2\n")
+}
+
 func TestTestReport_FirstResponse(t *testing.T) {
 	tr := TestReport{
 		ResponseTimings: responseTimings.ResponseTimings{
@@ -120,12 +147,14 @@ Requests per second (avg): 0.2
 
 Response code breakdown:`)
 	assert.Contains(output, "Percentile latency breakdown:")
+	assert.Contains(output, "Connection breakdown:")
 	assert.NotContains(output, "Timing breakdown:")
 	assert.NotContains(output, "No requests made...")
 
 	tr.ResponseCount = 1
 	output = tr.Output()
 	assert.Contains(output, "Timing breakdown:")
+	assert.Contains(output, "Connection details:")
 	assert.NotContains(output, "Response code breakdown:")
 	assert.NotContains(output, "Percentile latency breakdown:")
 	assert.NotContains(output, "No requests made...")
@@ -139,6 +168,152 @@ Response code breakdown:`)
 
 }
 
+func TestTestReport_OutputTimeouts(t *testing.T) {
+	assert := assert.New(t)
+	timedOut := responseTimings.ResponseTiming{
+		Response: &responseTimings.Response{
+			Status:       "Timeout (response header)",
+			Error:        "net/http: timeout awaiting response headers",
+			TimeoutPhase: responseTimings.PhaseResponseHeader,
+		},
+		Timing: &responseTimings.Timing{},
+	}
+	tr := TestReport{
+		ResponseCount:   2,
+		ResponseTimings: responseTimings.ResponseTimings{responseTiming, timedOut},
+	}
+
+	output := tr.Output()
+
+	assert.Contains(output, `Timeout breakdown:
+Timed out: 1 of 2 (50%)
+response header: ==========>           1x
+
+Percentile latency breakdown:`)
+	assert.NotContains(TestReport{ResponseCount: 2, ResponseTimings: responseTimings.ResponseTimings{responseTiming, responseTiming}}.Output(), "Timeout breakdown:")
+
+	tr.ResponseCount, tr.ResponseTimings = 1, responseTimings.ResponseTimings{timedOut}
+	output = tr.Output()
+	assert.Contains(output, "Error: net/http: timeout awaiting response headers")
+}
+
+func TestTestReport_OutputAdaptiveConcurrency(t *testing.T) {
+	assert := assert.New(t)
+	tr := TestReport{
+		Concurrency:     4,
+		ResponseCount:   2,
+		ResponseTimings: responseTimings.ResponseTimings{responseTiming, responseTiming},
+		Params:          Params{Adaptive: true},
+		Concurrencies:   []report.ConcurrencySample{{Limit: 1}, {Offset: 5 * time.Second, Limit: 2}},
+		StartTime:       time.Unix(0, 0),
+		FinishTime:      time.Unix(10, 0),
+	}
+
+	output := tr.Output()
+
+	assert.Contains(output, "Concurrency: adaptive, up to 4\n")
+	assert.Contains(output, `Concurrency over time:
+0s - 1s:           =====>                1.0
+`)
+	assert.Contains(output, "Equilibrium: 2.0 concurrent requests (2 - 2 over the second half of the test, limit 4)\n")
+
+	tr.Concurrencies = nil
+	assert.NotContains(tr.Output(), "Concurrency over time:")
+}
+
+func TestTestReport_OutputSourceLatencies(t *testing.T) {
+	assert := assert.New(t)
+	fromSource := func(localAddr string) responseTimings.ResponseTiming {
+		return responseTimings.ResponseTiming{
+			Response: &responseTimings.Response{StatusCode: 200},
+			Timing:   &responseTimings.Timing{Connection: responseTimings.Connection{LocalAddr: localAddr}},
+		}
+	}
+	tr := TestReport{
+		ResponseCount:   2,
+		ResponseTimings: responseTimings.ResponseTimings{fromSource("127.0.0.1:50001"), fromSource("127.0.0.2:50002")},
+	}
+
+	assert.Contains(tr.Output(), `Latency by source address:
+127.0.0.1: 1x  50th: 0ms  95th: 0ms  99th: 0ms  100th: 0ms
+127.0.0.2: 1x  50th: 0ms  95th: 0ms  99th: 0ms  100th: 0ms
+
+Body breakdown:`)
+
+	tr.ResponseTimings = responseTimings.ResponseTimings{fromSource("127.0.0.1:50001"), fromSource("127.0.0.1:50002")}
+	assert.NotContains(tr.Output(), "Latency by source address:")
+}
+
+func TestTestReport_OutputRedirects(t *testing.T) {
+	assert := assert.New(t)
+	hop := responseTimings.Redirect{
+		Url:        "http://www.example.com",
+		StatusCode: 301,
+		Timing:     responseTimings.Timing{GotConn: time.Unix(0, 0), Done: time.Unix(0, 0).Add(10 * time.Millisecond)},
+	}
+	redirected := responseTimings.ResponseTiming{
+		Request:  &responseTimings.Request{Url: "https://www.example.com"},
+		Response: &responseTimings.Response{StatusCode: 200},
+		Timing: &responseTimings.Timing{
+			GotConn:   time.Unix(1, 0),
+			Done:      time.Unix(1, 0).Add(10 * time.Millisecond),
+			Redirects: []responseTimings.Redirect{hop},
+		},
+	}
+	tr := TestReport{ResponseCount: 1, ResponseTimings: responseTimings.ResponseTimings{redirected}}
+
+	assert.Contains(tr.Output(), `
+Redirect waterfall:
+301 http://www.example.com  |===============               | 10ms
+200 https://www.example.com |               ===============| 10ms
+
+Connection details:`)
+
+	tr.ResponseCount, tr.ResponseTimings = 2, responseTimings.ResponseTimings{redirected, responseTiming}
+	assert.Contains(tr.Output(), `Redirect breakdown:
+Redirected: 1 of 2 (50%), 1 hops
+Added latency: 50th: 10ms  95th: 10ms  99th: 10ms  100th: 10ms (50% of their total)
+`)
+	assert.NotContains(TestReport{ResponseCount: 2, ResponseTimings: responseTimings.ResponseTimings{responseTiming, responseTiming}}.Output(), "Redirect breakdown:")
+}
+
+func TestTestReport_OutputBodies(t *testing.T) {
+	withBody := responseTimings.ResponseTiming{
+		Response: &responseTimings.Response{StatusCode: 200, WireSize: 1_000, DecodedSize: 4_000},
+		Timing:   &responseTimings.Timing{},
+	}
+	tr := TestReport{
+		ResponseCount:   2,
+		Duration:        time.Second,
+		ResponseTimings: responseTimings.ResponseTimings{withBody, withBody},
+	}
+
+	assert.Contains(t, tr.Output(), `Body breakdown:
+Received: 2.0 KB (8.0 KB decompressed) at 0.00 MB/s
+Body size: 50th: 4.0 KB  95th: 4.0 KB  99th: 4.0 KB  100th: 4.0 KB
+Transfer time: 50th: 0ms  95th: 0ms  99th: 0ms  100th: 0ms
+
+Connection breakdown:`)
+}
+
+func TestTestReport_OutputNetworkEmulation(t *testing.T) {
+	tr := TestReport{ResponseCount: 1, ResponseTimings: responseTimings.ResponseTimings{responseTiming}}
+	assert.NotContains(t, tr.Output(), "Network emulation:")
+
+	tr.Params.NetworkProfile = "3g"
+	assert.Contains(t, tr.Output(), `Requests per second (avg): 0
+Network emulation: 3g - 300ms latency (±50ms), 1600 kbps down, 750 kbps up, 0.0% drop rate
+`)
+}
+
+func TestTestReport_OutputPhasePercentiles(t *testing.T) {
+	tr := TestReport{ResponseCount: 1, Interactive: true, ResponseTimings: responseTimings.ResponseTimings{responseTiming}}
+
+	assert.Contains(t, tr.Output(), `Phase latency breakdown:
+Phase              Count     50th     95th     99th    100th
+`)
+}
+
 func TestTestReport_OutputErrorParsingTemplate(t *testing.T) {
 	logMock := new(mocks.Log)
 	Logger = logMock
@@ -158,3 +333,114 @@ func TestTestReport_OutputErrorParsingTemplate(t *testing.T) {
 	templateMock.AssertExpectations(t)
 	logMock.AssertExpectations(t)
 }
+
+func TestTestReport_ToRunData(t *testing.T) {
+	assert := assert.New(t)
+	oldVersion := Version
+	defer func() { Version = oldVersion }()
+	Version = "v1.2.3"
+	tr := TestReport{
+		Target:          "GET https://www.example.com",
+		Concurrency:     1,
+		Duration:        10 * time.Second,
+		ResponseCount:   1,
+		RequestRate:     0.1,
+		ResponseTimings: responseTimings.ResponseTimings{responseTiming},
+		Params:          params,
+		StartTime:       time.Unix(100, 0),
+		FinishTime:      time.Unix(110, 0),
+		Concurrencies:   []report.ConcurrencySample{{Limit: 1}},
+	}
+
+	runData := tr.ToRunData()
+
+	assert.Equal(rundata.VersionV2, runData.Version)
+	assert.Equal("v1.2.3", runData.LodeVersion)
+	assert.Equal(rundata.Params(params), runData.Params)
+	assert.Equal(rundata.CurrentEnvironment(), runData.Environment)
+	assert.Equal(tr.StartTime, runData.StartTime)
+	assert.Equal(tr.FinishTime, runData.FinishTime)
+	assert.Equal(tr.ResponseTimings, runData.ResponseTimings)
+	assert.Equal(tr.Concurrencies, runData.Concurrencies)
+	assert.Equal(tr.Target, runData.Target())
+}
+
+func TestTestReportFromRunData(t *testing.T) {
+	runData := rundata.RunDataV2{
+		Params:          rundata.Params(params),
+		Duration:        10 * time.Second,
+		ResponseCount:   1,
+		RequestRate:     0.1,
+		ResponseTimings: responseTimings.ResponseTimings{responseTiming},
+		Concurrencies:   []report.ConcurrencySample{{Limit: 1}},
+	}
+
+	tr := TestReportFromRunData(runData)
+
+	assert.Equal(t, TestReport{
+		Target:          "GET https://www.example.com",
+		Concurrency:     1,
+		Duration:        10 * time.Second,
+		ResponseCount:   1,
+		RequestRate:     0.1,
+		ResponseTimings: responseTimings.ResponseTimings{responseTiming},
+		Concurrencies:   []report.ConcurrencySample{{Limit: 1}},
+		Params:          params,
+		Interactive:     true,
+	}, tr)
+}
+
+func TestTestReport_OutputLatencyDistribution(t *testing.T) {
+	assert := assert.New(t)
+	tr := TestReport{ResponseCount: 2, ResponseTimings: responseTimings.ResponseTimings{responseTiming, responseTiming}}
+
+	output := tr.Output()
+	assert.Contains(output, "Latency distribution:\n")
+	assert.NotContains(output, "Latency over time:")
+
+	tr.Params.Heatmap = true
+	assert.Contains(tr.Output(), "Latency over time:\n")
+}
+
+func TestTestReport_OutputGroups(t *testing.T) {
+	tr := TestReport{ResponseCount: 2, ResponseTimings: responseTimings.ResponseTimings{responseTiming, responseTiming}}
+	assert.NotContains(t, tr.Output(), "Breakdown by")
+
+	tr.Params.GroupBy = "status"
+	assert.Contains(t, tr.Output(), "Breakdown by status:\n2xx: 2x  200: 2x\n")
+}
+
+func TestTestReport_OutputApdexAndSlo(t *testing.T) {
+	assert := assert.New(t)
+	tr := TestReport{ResponseCount: 2, ResponseTimings: responseTimings.ResponseTimings{responseTiming, responseTiming}}
+	assert.NotContains(tr.Output(), "Apdex")
+	assert.Empty(tr.ThresholdsMissed())
+
+	tr.Params.Apdex, tr.Params.Slo, tr.Params.FailOnSlo = 2*time.Millisecond, "99% under 2ms", true
+	assert.Contains(tr.Output(), `
+Apdex (T=2ms): 1.00 [Excellent] - satisfied 2, tolerating 0, frustrated 0
+SLO 99% under 2ms: 0.00% compliant (0 of 2), 10000.0% of error budget consumed - missed
+Threshold missed: SLO 99% under 2ms was missed, with 10000.0% of the error budget consumed
+
+Percentile latency breakdown:
+`)
+}
+
+func TestTestReport_OutputConfidenceAndConvergence(t *testing.T) {
+	assert := assert.New(t)
+	tr := TestReport{ResponseCount: 2, ResponseTimings: responseTimings.ResponseTimings{responseTiming, responseTiming}}
+	assert.NotContains(tr.Output(), "Confidence intervals")
+
+	tr.Params.ConfidenceIntervals = true
+	output := tr.Output()
+	assert.Contains(output, "\nLatency distribution:")
+	assert.Contains(output, "Confidence intervals (95%):\n50th: 2ms - 2ms\n")
+	assert.Contains(output, "Mean: 2ms - 2ms\n")
+	assert.NotContains(output, "Stopped early")
+
+	tr.Params.ConfidenceIntervals = false
+	tr.Converged, tr.Params.ConvergeWidth = true, 10*time.Millisecond
+	output = tr.Output()
+	assert.Contains(output, "Stopped early: the 99th percentile's confidence interval was within 10ms\n")
+	assert.Contains(output, "Confidence intervals (95%):\n")
+}
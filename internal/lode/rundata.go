@@ -1,50 +1,107 @@
 package lode
 
 import (
-	"encoding/json"
+	"fmt"
 	"github.com/JamesBalazs/lode/internal/files"
-	"github.com/JamesBalazs/lode/internal/responseTimings"
-	"gopkg.in/yaml.v3"
-	"time"
+	"github.com/JamesBalazs/lode/internal/files/rundata"
+	"github.com/JamesBalazs/lode/internal/redact"
+	"github.com/JamesBalazs/lode/internal/report"
+	"io"
+	"math"
+	"strings"
 )
 
-type RunDataV1 struct {
-	Version         string
-	Target          string
-	Concurrency     int
-	Duration        time.Duration
-	ResponseCount   int
-	RequestRate     float64
-	ResponseTimings responseTimings.ResponseTimings
-}
-
-func (runData RunDataV1) ToInteractiveTestReport() TestReport {
+func TestReportFromRunData(runData rundata.RunDataV2) TestReport {
+	// show timings at the resolution they were recorded at
+	resolution, _ := Params(runData.Params).TimingResolution()
+	runData.ResponseTimings.SetResolution(resolution)
 	return TestReport{
-		Target:          runData.Target,
-		Concurrency:     runData.Concurrency,
+		Target:          runData.Target(),
+		Concurrency:     runData.Params.Concurrency,
 		Duration:        runData.Duration,
 		ResponseCount:   runData.ResponseCount,
 		RequestRate:     runData.RequestRate,
+		WarmupCount:     len(runData.ResponseTimings) - len(runData.ResponseTimings.Measured()),
 		ResponseTimings: runData.ResponseTimings,
+		Concurrencies:   runData.Concurrencies,
+		Params:          Params(runData.Params),
+		StartTime:       runData.StartTime,
+		FinishTime:      runData.FinishTime,
 		Interactive:     true,
 	}
 }
 
-func RunDataFromFile(path string, format string) (runData RunDataV1) {
-	reader := files.Open(path)
+func RunDataFromFile(path string) rundata.RunDataV2 {
+	data, err := io.ReadAll(files.Open(path))
+	if err != nil {
+		Logger.Panicf("Error reading run file: %s", err.Error())
+	}
+
+	data, err = files.Decompress(data)
+	if err != nil {
+		Logger.Panicf("Error decompressing run file: %s", err.Error())
+	}
+
+	runData, err := rundata.Decode(data)
+	if err != nil {
+		Logger.Panicf("Error decoding run file: %s", err.Error())
+	}
+	return runData
+}
+
+// CheckRerunnable explains which flags a rerun needs. Run files from before v2 only recorded the target and
+// concurrency, and secrets redacted from a run file have to be given again rather than sent as the mask.
+func CheckRerunnable(runData rundata.RunDataV2, params Params) error {
+	var missing []string
+	if runData.UpgradedFrom != "" {
+		if params.Freq == 0 && params.Delay == 0 {
+			missing = append(missing, "--freq or --delay")
+		}
+		if params.MaxRequests == 0 && params.MaxTime == 0 {
+			missing = append(missing, "--maxRequests or --maxTime")
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("run file is version %s, which predates v2 and only recorded the target and concurrency - provide %s to rerun it",
+			runData.UpgradedFrom, strings.Join(missing, " and "))
+	}
 
-	var decoder files.Decoder
-	switch format {
-	case "json":
-		decoder = json.NewDecoder(reader)
-	case "yaml":
-		decoder = yaml.NewDecoder(reader)
-	default:
-		panic("invalid format")
+	var redacted []string
+	for _, header := range params.Headers {
+		if name, value, _ := strings.Cut(header, "="); strings.Contains(value, redact.Mask) {
+			redacted = append(redacted, name)
+		}
+	}
+	if len(redacted) != 0 {
+		missing = append(missing, strings.Join(redacted, ", ")+" with --header")
 	}
+	if redact.RedactedUrlPassword(params.Proxy) {
+		missing = append(missing, "the proxy password with --proxy")
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("secrets were redacted from the run file - provide %s to rerun it", strings.Join(missing, " and "))
+	}
+	return nil
+}
 
-	if err := decoder.Decode(&runData); err != nil {
-		panic(err)
+// FilterRunData keeps the responses matching every filter, each given as group=value, e.g. header:X-Served-By=web-2
+func FilterRunData(runData rundata.RunDataV2, filters []string) (rundata.RunDataV2, error) {
+	for _, filter := range filters {
+		key, group, ok := strings.Cut(filter, "=")
+		if !ok {
+			return runData, fmt.Errorf("invalid filter %q - expected groupby=value, e.g. status=5xx", filter)
+		}
+		grouper, err := report.NewGrouper(key)
+		if err != nil {
+			return runData, err
+		}
+		runData.ResponseTimings = grouper.Filter(runData.ResponseTimings, group)
+	}
+	if len(filters) > 0 {
+		runData.ResponseCount = len(runData.ResponseTimings.Measured())
+		if runData.Duration > 0 {
+			runData.RequestRate = math.Round((float64(runData.ResponseCount)/runData.Duration.Seconds())*100) / 100
+		}
 	}
-	return
+	return runData, nil
 }
@@ -0,0 +1,59 @@
+package lode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+func isTimeout(err error) bool {
+	var timeoutErr interface{ Timeout() bool }
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &timeoutErr) && timeoutErr.Timeout())
+}
+
+type idleBodyTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e idleBodyTimeoutError) Error() string {
+	return fmt.Sprintf("no response body received for %s", e.timeout)
+}
+
+func (e idleBodyTimeoutError) Timeout() bool {
+	return true
+}
+
+// idleTimeoutBody cancels the request if the server stops sending the body for longer than timeout.
+// The timer restarts after every read, so slow but steady bodies aren't cut off.
+type idleTimeoutBody struct {
+	io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	timedOut *int32
+}
+
+func newIdleTimeoutBody(body io.ReadCloser, timeout time.Duration, cancel context.CancelFunc) *idleTimeoutBody {
+	timedOut := new(int32)
+	timer := time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(timedOut, 1)
+		cancel()
+	})
+	return &idleTimeoutBody{ReadCloser: body, timeout: timeout, timer: timer, timedOut: timedOut}
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && atomic.LoadInt32(b.timedOut) == 1 {
+		return n, idleBodyTimeoutError{b.timeout}
+	}
+	b.timer.Reset(b.timeout)
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.ReadCloser.Close()
+}
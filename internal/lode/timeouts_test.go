@@ -0,0 +1,136 @@
+package lode
+
+import (
+	"context"
+	"errors"
+	"github.com/JamesBalazs/lode/internal/lode/mocks"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func timeoutParams(url string) Params {
+	return Params{Url: url, Method: "GET", Freq: 5, Concurrency: 1, MaxRequests: 1, Timeout: 5 * time.Second}
+}
+
+func TestIsTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isTimeout(context.DeadlineExceeded))
+	assert.True(isTimeout(idleBodyTimeoutError{time.Second}))
+	assert.True(isTimeout(&net.OpError{Op: "dial", Err: idleBodyTimeoutError{time.Second}}))
+	assert.False(isTimeout(errors.New("connection refused")))
+}
+
+func TestLode_RunRecordsHeaderTimeout(t *testing.T) {
+	assert := assert.New(t)
+	NewClient = defaultNewClient
+	Logger = new(mocks.Log)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	params := timeoutParams(server.URL)
+	params.HeaderTimeout = 50 * time.Millisecond
+	lode := New(params)
+
+	lode.Run()
+
+	response := lode.ResponseTimings[0].Response
+	assert.Equal(0, response.StatusCode)
+	assert.Equal("Timeout (response header)", response.Status)
+	assert.Equal(responseTimings.PhaseResponseHeader, response.TimeoutPhase)
+	assert.Contains(response.Error, "timeout awaiting response headers")
+	assert.Equal(1, lode.ExitCode)
+}
+
+func TestLode_RunRecordsTlsHandshakeTimeout(t *testing.T) {
+	assert := assert.New(t)
+	NewClient = defaultNewClient
+	Logger = new(mocks.Log)
+	// accepts connections but never completes a handshake
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer listener.Close()
+	go func() {
+		for {
+			if _, err := listener.Accept(); err != nil {
+				return
+			}
+		}
+	}()
+	params := timeoutParams("https://" + listener.Addr().String())
+	params.TlsTimeout = 50 * time.Millisecond
+	lode := New(params)
+
+	lode.Run()
+
+	response := lode.ResponseTimings[0].Response
+	assert.Equal(responseTimings.PhaseTlsHandshake, response.TimeoutPhase)
+	assert.Contains(response.Error, "TLS handshake timeout")
+}
+
+func TestLode_RunRecordsIdleBodyTimeout(t *testing.T) {
+	assert := assert.New(t)
+	NewClient = defaultNewClient
+	Logger = new(mocks.Log)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer server.Close()
+	params := timeoutParams(server.URL)
+	params.IdleBodyTimeout = 50 * time.Millisecond
+	lode := New(params)
+	lode.Interactive = true
+
+	lode.Run()
+
+	response := lode.ResponseTimings[0].Response
+	assert.Equal(200, response.StatusCode)
+	assert.Equal("partial", response.Body)
+	assert.Equal(responseTimings.PhaseBody, response.TimeoutPhase)
+	assert.Equal("no response body received for 50ms", response.Error)
+	assert.Equal(1, lode.ExitCode)
+}
+
+func TestLode_RunRecordsTruncatedBody(t *testing.T) {
+	assert := assert.New(t)
+	NewClient = defaultNewClient
+	Logger = new(mocks.Log)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		_, _ = w.Write([]byte("short"))
+	}))
+	defer server.Close()
+	lode := New(timeoutParams(server.URL))
+
+	lode.Run()
+
+	response := lode.ResponseTimings[0].Response
+	assert.Equal(200, response.StatusCode)
+	assert.Equal("unexpected EOF", response.Error)
+	assert.Equal(1, lode.ExitCode)
+}
+
+func TestLode_RunFailFastOnTimeout(t *testing.T) {
+	NewClient = defaultNewClient
+	logMock := new(mocks.Log)
+	logMock.On("Fatalf", "Request timed out during %s", "response header").Once()
+	Logger = logMock
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	params := timeoutParams(server.URL)
+	params.HeaderTimeout, params.FailFast = 50*time.Millisecond, true
+	lode := New(params)
+
+	lode.Run()
+
+	logMock.AssertExpectations(t)
+}
@@ -0,0 +1,108 @@
+package lode
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TlsConfig builds the client TLS configuration, loading the client certificate and CA bundle from disk.
+// It returns nil if no TLS options are set, so the transport defaults are used.
+func (p Params) TlsConfig() (*tls.Config, error) {
+	if p.TlsCert == "" && p.TlsKey == "" && p.TlsCaCert == "" && !p.TlsInsecure && p.TlsServerName == "" &&
+		p.TlsMinVersion == "" && p.TlsMaxVersion == "" && len(p.TlsCiphers) == 0 && len(p.TlsAlpn) == 0 {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		InsecureSkipVerify: p.TlsInsecure,
+		ServerName:         p.TlsServerName,
+		NextProtos:         p.TlsAlpn,
+	}
+
+	if (p.TlsCert == "") != (p.TlsKey == "") {
+		return nil, fmt.Errorf("tlscert and tlskey must be provided together")
+	}
+	if p.TlsCert != "" {
+		certificate, err := tls.LoadX509KeyPair(p.TlsCert, p.TlsKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate %q: %s", p.TlsCert, err.Error())
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	if p.TlsCaCert != "" {
+		pem, err := os.ReadFile(p.TlsCaCert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read tlscacert: %s", err.Error())
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in tlscacert %q", p.TlsCaCert)
+		}
+	}
+
+	var err error
+	if config.MinVersion, err = parseTlsVersion("tlsminversion", p.TlsMinVersion); err != nil {
+		return nil, err
+	}
+	if config.MaxVersion, err = parseTlsVersion("tlsmaxversion", p.TlsMaxVersion); err != nil {
+		return nil, err
+	}
+	if config.MinVersion != 0 && config.MaxVersion != 0 && config.MinVersion > config.MaxVersion {
+		return nil, fmt.Errorf("tlsminversion must not be greater than tlsmaxversion")
+	}
+
+	if config.CipherSuites, err = parseTlsCiphers(p.TlsCiphers); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func parseTlsVersion(name string, version string) (uint16, error) {
+	if version == "" {
+		return 0, nil
+	}
+	if parsed, ok := tlsVersions[strings.TrimPrefix(version, "TLS")]; ok {
+		return parsed, nil
+	}
+	return 0, fmt.Errorf("invalid %s %q - valid options are 1.0, 1.1, 1.2 and 1.3", name, version)
+}
+
+// parseTlsCiphers looks up cipher suites by their IANA names, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+// TLS 1.3 suites are not configurable in Go, so only apply to TLS 1.2 and below.
+func parseTlsCiphers(names []string) (ids []uint16, err error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	suites := make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		suites[suite.Name] = suite.ID
+	}
+	for _, name := range names {
+		id, ok := suites[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown tls cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
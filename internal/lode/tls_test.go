@@ -0,0 +1,176 @@
+package lode
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	certFile    string
+	keyFile     string
+}
+
+// newTestCertificate creates a certificate signed by parent, or a self-signed CA if parent is nil,
+// and writes it to PEM files in dir.
+func newTestCertificate(t *testing.T, dir string, name string, parent *testCertificate) testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.certificate, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	certificate, _ := x509.ParseCertificate(der)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	result := testCertificate{
+		certificate: certificate,
+		key:         key,
+		certFile:    filepath.Join(dir, name+".crt"),
+		keyFile:     filepath.Join(dir, name+".key"),
+	}
+	assert.Nil(t, os.WriteFile(result.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, os.WriteFile(result.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return result
+}
+
+func TestParams_TlsConfig_NoOptions(t *testing.T) {
+	config, err := Params{}.TlsConfig()
+
+	assert.Nil(t, config)
+	assert.Nil(t, err)
+}
+
+func TestParams_TlsConfig(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	ca := newTestCertificate(t, dir, "ca", nil)
+	client := newTestCertificate(t, dir, "client", &ca)
+
+	config, err := Params{
+		TlsCert:       client.certFile,
+		TlsKey:        client.keyFile,
+		TlsCaCert:     ca.certFile,
+		TlsInsecure:   true,
+		TlsServerName: "lode.test",
+		TlsMinVersion: "1.2",
+		TlsMaxVersion: "TLS1.3",
+		TlsCiphers:    []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "tls_ecdhe_rsa_with_aes_256_gcm_sha384"},
+		TlsAlpn:       []string{"h2", "http/1.1"},
+	}.TlsConfig()
+
+	assert.Nil(err)
+	assert.Len(config.Certificates, 1)
+	assert.NotNil(config.RootCAs)
+	assert.True(config.InsecureSkipVerify)
+	assert.Equal("lode.test", config.ServerName)
+	assert.Equal(uint16(tls.VersionTLS12), config.MinVersion)
+	assert.Equal(uint16(tls.VersionTLS13), config.MaxVersion)
+	assert.Equal([]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, config.CipherSuites)
+	assert.Equal([]string{"h2", "http/1.1"}, config.NextProtos)
+}
+
+func TestParams_TlsConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, dir, "ca", nil)
+	notPem := filepath.Join(dir, "not.pem")
+	assert.Nil(t, os.WriteFile(notPem, []byte("not a certificate"), 0600))
+
+	for _, test := range []struct {
+		params   Params
+		expected string
+	}{
+		{Params{TlsCert: ca.certFile}, "tlscert and tlskey must be provided together"},
+		{Params{TlsKey: ca.keyFile}, "tlscert and tlskey must be provided together"},
+		{Params{TlsCert: filepath.Join(dir, "missing.crt"), TlsKey: ca.keyFile}, `unable to load client certificate "` + filepath.Join(dir, "missing.crt") + `": open ` + filepath.Join(dir, "missing.crt") + ": no such file or directory"},
+		{Params{TlsCaCert: filepath.Join(dir, "missing.crt")}, "unable to read tlscacert: open " + filepath.Join(dir, "missing.crt") + ": no such file or directory"},
+		{Params{TlsCaCert: notPem}, `no PEM certificates found in tlscacert "` + notPem + `"`},
+		{Params{TlsMinVersion: "1.4"}, `invalid tlsminversion "1.4" - valid options are 1.0, 1.1, 1.2 and 1.3`},
+		{Params{TlsMaxVersion: "ssl3"}, `invalid tlsmaxversion "ssl3" - valid options are 1.0, 1.1, 1.2 and 1.3`},
+		{Params{TlsMinVersion: "1.3", TlsMaxVersion: "1.2"}, "tlsminversion must not be greater than tlsmaxversion"},
+		{Params{TlsCiphers: []string{"TLS_NOT_A_CIPHER"}}, `unknown tls cipher suite "TLS_NOT_A_CIPHER"`},
+	} {
+		config, err := test.params.TlsConfig()
+
+		assert.Nil(t, config)
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func TestNewClient_MutualTls(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	ca := newTestCertificate(t, dir, "ca", nil)
+	serverCertificate := newTestCertificate(t, dir, "lode.test", &ca)
+	client := newTestCertificate(t, dir, "client", &ca)
+
+	serverKeyPair, err := tls.LoadX509KeyPair(serverCertificate.certFile, serverCertificate.keyFile)
+	assert.Nil(err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.certificate)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.ServerName + " " + r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverKeyPair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	params := Params{TlsCaCert: ca.certFile, TlsServerName: "lode.test", TlsMaxVersion: "1.2"}
+	_, err = doGet(t, defaultNewClient(params), server.URL)
+	assert.NotNil(err)
+
+	params.TlsCert, params.TlsKey = client.certFile, client.keyFile
+	request, _ := http.NewRequest("GET", server.URL, nil)
+	response, err := defaultNewClient(params).Do(request)
+	assert.Nil(err)
+	body := make([]byte, 64)
+	n, _ := response.Body.Read(body)
+	response.Body.Close()
+
+	assert.Equal("lode.test client", string(body[:n]))
+	assert.Equal(uint16(tls.VersionTLS12), response.TLS.Version)
+}
+
+func TestNewClient_AlpnWithoutH2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(okHandler))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	response, err := doGet(t, defaultNewClient(Params{TlsInsecure: true, TlsAlpn: []string{"http/1.1"}}), server.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, response.ProtoMajor)
+}
@@ -0,0 +1,39 @@
+package lode
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const unixScheme = "unix://"
+
+// Target returns the URL to send requests to, and the Unix socket to connect to if there is one.
+// unix:// URLs give the socket file followed by the HTTP path, e.g. unix:///var/run/app.sock/health,
+// and are sent as http://localhost/health.
+func (p Params) Target() (url string, unixSocket string, err error) {
+	if !strings.HasPrefix(p.Url, unixScheme) {
+		return p.Url, p.UnixSocket, nil
+	}
+	if p.UnixSocket != "" {
+		return "", "", fmt.Errorf("unixsocket cannot be combined with a unix:// url")
+	}
+
+	path, query, _ := strings.Cut(strings.TrimPrefix(p.Url, unixScheme), "?")
+	for i := 1; i <= len(path); i++ {
+		if i < len(path) && path[i] != '/' {
+			continue
+		}
+		if info, statErr := os.Stat(path[:i]); statErr == nil && info.Mode()&os.ModeSocket != 0 {
+			url = "http://localhost" + path[i:]
+			if path[i:] == "" {
+				url += "/"
+			}
+			if query != "" {
+				url += "?" + query
+			}
+			return url, path[:i], nil
+		}
+	}
+	return "", "", fmt.Errorf("no unix socket found in url %q", p.Url)
+}
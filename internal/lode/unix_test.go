@@ -0,0 +1,75 @@
+package lode
+
+import (
+	"github.com/JamesBalazs/lode/internal/lode/mocks"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startUnixServer serves the request path and Host header on a socket in a temporary directory.
+func startUnixServer(t *testing.T) (socket string) {
+	socket = filepath.Join(t.TempDir(), "lode.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.Nil(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host + " " + r.URL.RequestURI()))
+	})}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { server.Close() })
+	return
+}
+
+func TestParams_Target(t *testing.T) {
+	assert := assert.New(t)
+	socket := startUnixServer(t)
+
+	for _, test := range []struct {
+		params Params
+		url    string
+		socket string
+	}{
+		{Params{Url: "https://www.example.com/path"}, "https://www.example.com/path", ""},
+		{Params{Url: "http://api.internal/health", UnixSocket: socket}, "http://api.internal/health", socket},
+		{Params{Url: "unix://" + socket}, "http://localhost/", socket},
+		{Params{Url: "unix://" + socket + "/v1/health?verbose=true"}, "http://localhost/v1/health?verbose=true", socket},
+	} {
+		url, unixSocket, err := test.params.Target()
+
+		assert.Nil(err)
+		assert.Equal(test.url, url)
+		assert.Equal(test.socket, unixSocket)
+	}
+
+	_, _, err := Params{Url: "unix://" + filepath.Dir(socket) + "/missing.sock/health"}.Target()
+	assert.EqualError(err, `no unix socket found in url "unix://`+filepath.Dir(socket)+`/missing.sock/health"`)
+	_, _, err = Params{Url: "unix://" + socket, UnixSocket: socket}.Target()
+	assert.EqualError(err, "unixsocket cannot be combined with a unix:// url")
+}
+
+func TestLode_RunUnixSocket(t *testing.T) {
+	assert := assert.New(t)
+	NewClient = defaultNewClient
+	Logger = new(mocks.Log)
+	socket := startUnixServer(t)
+
+	for _, params := range []Params{
+		{Url: "unix://" + socket + "/health"},
+		{Url: "http://localhost/health", UnixSocket: socket},
+	} {
+		params.Method, params.Freq, params.Concurrency, params.MaxRequests, params.Timeout = "GET", 5, 1, 1, time.Second
+		lode := New(params)
+		lode.Interactive = true
+
+		lode.Run()
+
+		responseTiming := lode.ResponseTimings[0]
+		assert.Equal(200, responseTiming.Response.StatusCode)
+		assert.Equal("localhost /health", responseTiming.Response.Body)
+		assert.Equal(socket, responseTiming.Timing.Connection.RemoteAddr)
+		assert.Equal("GET "+params.Url, NewTestReport(lode).Target)
+	}
+}
@@ -0,0 +1,103 @@
+package redact
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// segment is one step of a JSONPath, either an object key or an array index.
+// A wildcard matches every key or index, and a recursive segment (..key) matches at any depth.
+type segment struct {
+	key       string
+	index     int
+	isIndex   bool
+	wildcard  bool
+	recursive bool
+}
+
+// jsonPath supports the subset of JSONPath needed to point at fields, e.g.
+// $.token, $.data.users[*].password, $.items[0].secret or $..apiKey
+type jsonPath []segment
+
+func parseJsonPath(path string) (result jsonPath, err error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid redaction field %q: %s", path, reason)
+	}
+
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	for len(rest) > 0 {
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, invalid("missing ]")
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if inner == "*" {
+				result = append(result, segment{isIndex: true, wildcard: true})
+			} else if quoted := strings.Trim(inner, `'"`); quoted != inner {
+				result = append(result, segment{key: quoted})
+			} else if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+				result = append(result, segment{isIndex: true, index: index})
+			} else {
+				return nil, invalid(fmt.Sprintf("bad index [%s]", inner))
+			}
+		case strings.HasPrefix(rest, "."):
+			recursive := strings.HasPrefix(rest, "..")
+			rest = strings.TrimLeft(rest, ".")
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			rest = rest[end:]
+			if key == "" {
+				return nil, invalid("empty key")
+			}
+			result = append(result, segment{key: key, wildcard: key == "*", recursive: recursive})
+		default:
+			// allow the leading dot to be omitted, e.g. data.token
+			if len(result) > 0 {
+				return nil, invalid("expected . or [")
+			}
+			rest = "." + rest
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, invalid("path is empty")
+	}
+	return
+}
+
+// redact replaces every value matched by the path with Mask. Objects and arrays are
+// modified in place, so the returned node only differs when the whole node is masked.
+func (p jsonPath) redact(node interface{}, changed *bool) interface{} {
+	if len(p) == 0 {
+		*changed = true
+		return Mask
+	}
+
+	current, rest := p[0], p[1:]
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if !current.isIndex && (current.wildcard || key == current.key) {
+				value[key] = rest.redact(child, changed)
+			} else if current.recursive {
+				value[key] = p.redact(child, changed)
+			}
+		}
+	case []interface{}:
+		for i, child := range value {
+			if current.isIndex && (current.wildcard || i == current.index) {
+				value[i] = rest.redact(child, changed)
+			} else if current.recursive {
+				value[i] = p.redact(child, changed)
+			}
+		}
+	}
+	return node
+}
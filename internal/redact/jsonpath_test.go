@@ -0,0 +1,75 @@
+package redact
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseJsonPath(t *testing.T) {
+	assert := assert.New(t)
+
+	path, err := parseJsonPath("$.data.users[*].password")
+	assert.Nil(err)
+	assert.Equal(jsonPath{
+		{key: "data"},
+		{key: "users"},
+		{isIndex: true, wildcard: true},
+		{key: "password"},
+	}, path)
+
+	path, err = parseJsonPath("$..apiKey")
+	assert.Nil(err)
+	assert.Equal(jsonPath{{key: "apiKey", recursive: true}}, path)
+
+	path, err = parseJsonPath("items[2]['x-token']")
+	assert.Nil(err)
+	assert.Equal(jsonPath{{key: "items"}, {isIndex: true, index: 2}, {key: "x-token"}}, path)
+
+	path, err = parseJsonPath("$.*")
+	assert.Nil(err)
+	assert.Equal(jsonPath{{key: "*", wildcard: true}}, path)
+}
+
+func TestParseJsonPath_Invalid(t *testing.T) {
+	for path, message := range map[string]string{
+		"$":         "path is empty",
+		"$.items[0": "missing ]",
+		"$.items[]": "bad index []",
+		"$.a.":      "empty key",
+	} {
+		_, err := parseJsonPath(path)
+		assert.EqualError(t, err, "invalid redaction field \""+path+"\": "+message)
+	}
+}
+
+func TestJsonPath_Redact(t *testing.T) {
+	document := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"secret": "a"},
+			map[string]interface{}{"secret": "b"},
+		},
+	}
+	path, _ := parseJsonPath("$.items[1].secret")
+	changed := false
+
+	path.redact(document, &changed)
+
+	assert.True(t, changed)
+	assert.Equal(t, map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"secret": "a"},
+			map[string]interface{}{"secret": Mask},
+		},
+	}, document)
+}
+
+func TestJsonPath_RedactNoMatch(t *testing.T) {
+	document := map[string]interface{}{"name": "a"}
+	path, _ := parseJsonPath("$.secret")
+	changed := false
+
+	path.redact(document, &changed)
+
+	assert.False(t, changed)
+	assert.Equal(t, map[string]interface{}{"name": "a"}, document)
+}
@@ -0,0 +1,208 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/files/rundata"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const Mask = "[REDACTED]"
+
+// urlPasswordMask is what url.URL.Redacted puts in place of a password
+const urlPasswordMask = "xxxxx"
+
+var DefaultHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// defaultHeaderPattern catches API-key style headers such as X-Api-Key or X-Auth-Token
+var defaultHeaderPattern = regexp.MustCompile(`(?i)(api[-_]?key|auth[-_]?token|access[-_]?token|secret)`)
+
+type Rules struct {
+	Headers    []string
+	Fields     []string
+	Patterns   []string
+	NoDefaults bool
+}
+
+// Redactor masks secrets in headers and bodies. The zero value redacts nothing.
+type Redactor struct {
+	headers  map[string]bool
+	defaults bool
+	fields   []jsonPath
+	patterns []*regexp.Regexp
+}
+
+func New(rules Rules) (redactor Redactor, err error) {
+	redactor.headers = make(map[string]bool)
+	redactor.defaults = !rules.NoDefaults
+	if redactor.defaults {
+		for _, name := range DefaultHeaders {
+			redactor.headers[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+	for _, name := range rules.Headers {
+		redactor.headers[http.CanonicalHeaderKey(name)] = true
+	}
+
+	for _, field := range rules.Fields {
+		path, err := parseJsonPath(field)
+		if err != nil {
+			return Redactor{}, err
+		}
+		redactor.fields = append(redactor.fields, path)
+	}
+
+	for _, pattern := range rules.Patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return Redactor{}, fmt.Errorf("invalid redaction pattern %q: %s", pattern, err.Error())
+		}
+		redactor.patterns = append(redactor.patterns, compiled)
+	}
+	return
+}
+
+func (r Redactor) RedactsHeader(name string) bool {
+	return r.headers[http.CanonicalHeaderKey(name)] || (r.defaults && defaultHeaderPattern.MatchString(name))
+}
+
+// Header returns a copy of header with redacted values masked.
+func (r Redactor) Header(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+	result := make(http.Header, len(header))
+	for name, values := range header {
+		redacted := make([]string, len(values))
+		for i, value := range values {
+			if r.RedactsHeader(name) {
+				redacted[i] = Mask
+			} else {
+				redacted[i] = r.text(value)
+			}
+		}
+		result[name] = redacted
+	}
+	return result
+}
+
+// HeaderStrings masks values of headers in the X-SomeHeader=value form used by Params.
+func (r Redactor) HeaderStrings(headers []string) []string {
+	if headers == nil {
+		return nil
+	}
+	result := make([]string, len(headers))
+	for i, headerString := range headers {
+		name, value, found := strings.Cut(headerString, "=")
+		if !found {
+			result[i] = headerString
+		} else if r.RedactsHeader(name) {
+			result[i] = name + "=" + Mask
+		} else {
+			result[i] = name + "=" + r.text(value)
+		}
+	}
+	return result
+}
+
+// Body masks JSON fields matching the redaction paths, then any text matching the redaction patterns.
+func (r Redactor) Body(body string) string {
+	if len(r.fields) > 0 {
+		body = r.jsonFields(body)
+	}
+	return r.text(body)
+}
+
+func (r Redactor) Response(response *responseTimings.Response) {
+	if response == nil {
+		return
+	}
+	response.Header.HttpHeader = r.Header(response.Header.HttpHeader)
+	response.Body = r.Body(response.Body)
+}
+
+func (r Redactor) Request(request *responseTimings.Request) {
+	if request == nil {
+		return
+	}
+	request.Header.HttpHeader = r.Header(request.Header.HttpHeader)
+	request.Body = r.Body(request.Body)
+}
+
+func (r Redactor) ResponseTiming(responseTiming responseTimings.ResponseTiming) {
+	r.Request(responseTiming.Request)
+	r.Response(responseTiming.Response)
+}
+
+func (r Redactor) ResponseTimings(responseTimings responseTimings.ResponseTimings) {
+	for _, responseTiming := range responseTimings {
+		r.ResponseTiming(responseTiming)
+	}
+}
+
+// Params masks the request headers and body recorded in a run file, and any proxy password.
+func (r Redactor) Params(params *rundata.Params) {
+	params.Headers = r.HeaderStrings(params.Headers)
+	params.Body = r.Body(params.Body)
+	if r.defaults {
+		params.Proxy = redactUrlPassword(params.Proxy)
+	}
+}
+
+func redactUrlPassword(rawUrl string) string {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil || parsed.User == nil {
+		return rawUrl
+	}
+	if _, hasPassword := parsed.User.Password(); !hasPassword {
+		return rawUrl
+	}
+	return parsed.Redacted()
+}
+
+// RedactedUrlPassword reports whether a URL's password was masked, e.g. in a proxy URL from a run file
+func RedactedUrlPassword(rawUrl string) bool {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil || parsed.User == nil {
+		return false
+	}
+	password, _ := parsed.User.Password()
+	return password == urlPasswordMask
+}
+
+func (r Redactor) text(text string) string {
+	for _, pattern := range r.patterns {
+		text = pattern.ReplaceAllString(text, Mask)
+	}
+	return text
+}
+
+func (r Redactor) jsonFields(body string) string {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return body
+	}
+
+	changed := false
+	for _, path := range r.fields {
+		document = path.redact(document, &changed)
+	}
+	if !changed {
+		return body
+	}
+
+	buffer := bytes.Buffer{}
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(document); err != nil {
+		return body
+	}
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
@@ -0,0 +1,67 @@
+package report
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"time"
+)
+
+// Apdex scores responses from 0 to 1, counting those within the threshold T as satisfied,
+// those within 4T as tolerating, and slower or failed responses as frustrated
+type Apdex struct {
+	Threshold  time.Duration
+	Satisfied  int
+	Tolerating int
+	Frustrated int
+}
+
+func BuildApdex(timings responseTimings.ResponseTimings, threshold time.Duration) (apdex Apdex) {
+	apdex.Threshold = threshold
+	for _, responseTiming := range timings {
+		latency := responseTiming.Timing.TotalDuration()
+		switch {
+		case failed(responseTiming.Response):
+			apdex.Frustrated++
+		case latency <= threshold:
+			apdex.Satisfied++
+		case latency <= 4*threshold:
+			apdex.Tolerating++
+		default:
+			apdex.Frustrated++
+		}
+	}
+	return
+}
+
+// failed treats a missing response as a failure, as well as the responses that give lode a non-zero exit code
+func failed(response *responseTimings.Response) bool {
+	return response == nil || response.Failed()
+}
+
+func (a Apdex) Score() float64 {
+	total := a.Satisfied + a.Tolerating + a.Frustrated
+	if total == 0 {
+		return 0
+	}
+	return (float64(a.Satisfied) + float64(a.Tolerating)/2) / float64(total)
+}
+
+// Rating uses the standard Apdex bands
+func (a Apdex) Rating() string {
+	switch score := a.Score(); {
+	case score >= 0.94:
+		return "Excellent"
+	case score >= 0.85:
+		return "Good"
+	case score >= 0.7:
+		return "Fair"
+	case score >= 0.5:
+		return "Poor"
+	}
+	return "Unacceptable"
+}
+
+func (a Apdex) String() string {
+	return fmt.Sprintf("Apdex (T=%s): %.2f [%s] - satisfied %d, tolerating %d, frustrated %d\n",
+		a.Threshold, a.Score(), a.Rating(), a.Satisfied, a.Tolerating, a.Frustrated)
+}
@@ -0,0 +1,46 @@
+package report
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func scoredResponse(statusCode int, latency time.Duration) responseTimings.ResponseTiming {
+	return responseTimings.ResponseTiming{
+		Response: &responseTimings.Response{StatusCode: statusCode},
+		Timing:   &responseTimings.Timing{GotConn: time.Unix(0, 0), Done: time.Unix(0, 0).Add(latency)},
+	}
+}
+
+func TestBuildApdex(t *testing.T) {
+	assert := assert.New(t)
+	timings := responseTimings.ResponseTimings{
+		scoredResponse(200, 100*time.Millisecond),
+		scoredResponse(200, 500*time.Millisecond),
+		scoredResponse(200, 1500*time.Millisecond),
+		scoredResponse(200, 3*time.Second),
+		scoredResponse(503, 10*time.Millisecond),
+		scoredResponse(0, 10*time.Millisecond),
+	}
+
+	apdex := BuildApdex(timings, 500*time.Millisecond)
+
+	assert.Equal(Apdex{Threshold: 500 * time.Millisecond, Satisfied: 2, Tolerating: 1, Frustrated: 3}, apdex)
+	assert.InDelta(2.5/6, apdex.Score(), 0.0001)
+	assert.Equal("Unacceptable", apdex.Rating())
+	assert.Equal("Apdex (T=500ms): 0.42 [Unacceptable] - satisfied 2, tolerating 1, frustrated 3\n", apdex.String())
+}
+
+func TestApdex_Rating(t *testing.T) {
+	for rating, apdex := range map[string]Apdex{
+		"Excellent":    {Satisfied: 94, Frustrated: 6},
+		"Good":         {Satisfied: 85, Frustrated: 15},
+		"Fair":         {Satisfied: 70, Frustrated: 30},
+		"Poor":         {Satisfied: 50, Frustrated: 50},
+		"Unacceptable": {},
+	} {
+		assert.Equal(t, rating, apdex.Rating())
+	}
+}
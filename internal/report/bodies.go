@@ -0,0 +1,79 @@
+package report
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/montanaflynn/stats"
+	"strings"
+	"time"
+)
+
+type BodySummary struct {
+	Count        int
+	WireBytes    int64
+	DecodedBytes int64
+	Duration     time.Duration
+	Sizes        map[float64]int64 // percentiles of the decompressed body size
+	Transfer     LatencyPercentiles
+}
+
+func BuildBodySummary(timings responseTimings.ResponseTimings, duration time.Duration, resolution time.Duration) (summary BodySummary) {
+	summary = BodySummary{Duration: duration, Sizes: make(map[float64]int64)}
+	var sizes, transfers []float64
+	for _, responseTiming := range timings {
+		response, timing := responseTiming.Response, responseTiming.Timing
+		if response == nil {
+			continue
+		}
+		summary.Count++
+		summary.WireBytes += response.WireSize
+		summary.DecodedBytes += response.DecodedSize
+		sizes = append(sizes, float64(response.DecodedSize))
+		if timing != nil && !timing.FirstByte.IsZero() && !timing.Done.IsZero() {
+			transfers = append(transfers, float64(timing.ResponseTransferDuration()))
+		}
+	}
+	for _, percentile := range summaryPercentiles {
+		size, err := stats.Percentile(sizes, percentile)
+		if err != nil { // no responses
+			size = 0
+		}
+		summary.Sizes[percentile] = int64(size)
+	}
+	summary.Transfer = buildPercentiles(transfers, nil, resolution)
+	return
+}
+
+// Throughput is the rate bytes were received at over the whole test, in MB/s
+func (b BodySummary) Throughput() float64 {
+	if b.Duration <= 0 {
+		return 0
+	}
+	return float64(b.WireBytes) / 1e6 / b.Duration.Seconds()
+}
+
+func (b BodySummary) String() string {
+	received := formatBytes(b.WireBytes)
+	if b.DecodedBytes != b.WireBytes {
+		received += fmt.Sprintf(" (%s decompressed)", formatBytes(b.DecodedBytes))
+	}
+	sizes := make([]string, len(summaryPercentiles))
+	for i, percentile := range summaryPercentiles {
+		sizes[i] = fmt.Sprintf("%s: %s", FormatPercentile(percentile), formatBytes(b.Sizes[percentile]))
+	}
+	return fmt.Sprintf("Received: %s at %.2f MB/s\nBody size: %s\nTransfer time: %s\n",
+		received, b.Throughput(), strings.Join(sizes, "  "), b.Transfer.Summary())
+}
+
+func formatBytes(bytes int64) string {
+	switch {
+	case bytes < 1e3:
+		return fmt.Sprintf("%d B", bytes)
+	case bytes < 1e6:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/1e3)
+	case bytes < 1e9:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/1e6)
+	default:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/1e9)
+	}
+}
@@ -0,0 +1,43 @@
+package report
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func bodyTiming(wireSize, decodedSize int64, transfer time.Duration) responseTimings.ResponseTiming {
+	return responseTimings.ResponseTiming{
+		Response: &responseTimings.Response{WireSize: wireSize, DecodedSize: decodedSize},
+		Timing:   &responseTimings.Timing{FirstByte: time.Unix(0, 0), Done: time.Unix(0, 0).Add(transfer)},
+	}
+}
+
+func TestBuildBodySummary(t *testing.T) {
+	assert := assert.New(t)
+	timings := responseTimings.ResponseTimings{
+		bodyTiming(500_000, 2_000_000, 10*time.Millisecond),
+		bodyTiming(1_500_000, 1_500_000, 30*time.Millisecond),
+		{Response: &responseTimings.Response{}, Timing: &responseTimings.Timing{}},
+		{},
+	}
+
+	summary := BuildBodySummary(timings, 2*time.Second, time.Millisecond)
+
+	assert.Equal(3, summary.Count)
+	assert.Equal(int64(2_000_000), summary.WireBytes)
+	assert.Equal(int64(3_500_000), summary.DecodedBytes)
+	assert.Equal(1.0, summary.Throughput())
+	assert.Equal(`Received: 2.0 MB (3.5 MB decompressed) at 1.00 MB/s
+Body size: 50th: 750.0 KB  95th: 1.8 MB  99th: 1.8 MB  100th: 2.0 MB
+Transfer time: 50th: 10ms  95th: 20ms  99th: 20ms  100th: 30ms
+`, summary.String())
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "999 B", formatBytes(999))
+	assert.Equal(t, "1.5 KB", formatBytes(1_500))
+	assert.Equal(t, "2.0 MB", formatBytes(2_000_000))
+	assert.Equal(t, "3.2 GB", formatBytes(3_200_000_000))
+}
@@ -0,0 +1,111 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ConcurrencySample is a change to the concurrency limit, at an offset from the start of the run
+type ConcurrencySample struct {
+	Offset time.Duration
+	Limit  int
+}
+
+// ConcurrencyPeriod is the time-weighted average limit over part of a run
+type ConcurrencyPeriod struct {
+	Start   time.Duration
+	End     time.Duration
+	Average float64
+}
+
+// ConcurrencyTimeline shows how an adaptive concurrency limit moved over a run, and where it settled
+type ConcurrencyTimeline struct {
+	Samples     []ConcurrencySample
+	Periods     []ConcurrencyPeriod
+	Max         int     // the limit never goes above it
+	Equilibrium float64 // average limit over the second half of the run
+	SettledMin  int
+	SettledMax  int
+}
+
+func BuildConcurrencyTimeline(samples []ConcurrencySample, duration time.Duration, max int, periods int) (timeline ConcurrencyTimeline) {
+	timeline = ConcurrencyTimeline{Samples: samples, Max: max}
+	if len(samples) == 0 || duration <= 0 {
+		return
+	}
+	for i := 0; i < periods; i++ {
+		start, end := duration*time.Duration(i)/time.Duration(periods), duration*time.Duration(i+1)/time.Duration(periods)
+		timeline.Periods = append(timeline.Periods, ConcurrencyPeriod{Start: start, End: end, Average: timeline.average(start, end)})
+	}
+	// the first half is left out, as the limit starts low and takes a while to find its level
+	half := duration / 2
+	timeline.Equilibrium = timeline.average(half, duration)
+	timeline.SettledMin, timeline.SettledMax = timeline.limitAt(half), timeline.limitAt(half)
+	for _, sample := range samples {
+		if sample.Offset > half && sample.Offset <= duration {
+			if sample.Limit < timeline.SettledMin {
+				timeline.SettledMin = sample.Limit
+			}
+			if sample.Limit > timeline.SettledMax {
+				timeline.SettledMax = sample.Limit
+			}
+		}
+	}
+	return
+}
+
+// limitAt is the limit in place at an offset into the run
+func (c ConcurrencyTimeline) limitAt(offset time.Duration) (limit int) {
+	for _, sample := range c.Samples {
+		if sample.Offset > offset {
+			break
+		}
+		limit = sample.Limit
+	}
+	return
+}
+
+// average is the time-weighted mean limit between two offsets into the run
+func (c ConcurrencyTimeline) average(from, to time.Duration) float64 {
+	if to <= from {
+		return float64(c.limitAt(from))
+	}
+	total := 0.0
+	for i, sample := range c.Samples {
+		start, end := sample.Offset, to
+		if i+1 < len(c.Samples) && c.Samples[i+1].Offset < to {
+			end = c.Samples[i+1].Offset
+		}
+		if start < from {
+			start = from
+		}
+		if end > start {
+			total += float64(sample.Limit) * float64(end-start)
+		}
+	}
+	return total / float64(to-from)
+}
+
+// String draws a bar for each period, scaled to the highest limit allowed, followed by the equilibrium
+func (c ConcurrencyTimeline) String() string {
+	builder := strings.Builder{}
+	for _, period := range c.Periods {
+		bar := strings.Repeat("=", int(period.Average*20/float64(c.Max))) + ">"
+		precision := labelPrecision(period.End - period.Start)
+		label := fmt.Sprintf("%s - %s:", period.Start.Round(precision), period.End.Round(precision))
+		builder.WriteString(fmt.Sprintf("%-18s %-21s %.1f\n", label, bar, period.Average))
+	}
+	builder.WriteString(fmt.Sprintf("Equilibrium: %.1f concurrent requests (%d - %d over the second half of the test, limit %d)\n",
+		c.Equilibrium, c.SettledMin, c.SettledMax, c.Max))
+	return builder.String()
+}
+
+// labelPrecision keeps period labels distinct without showing more digits than needed
+func labelPrecision(period time.Duration) time.Duration {
+	precision := time.Millisecond
+	for precision*10 <= period && precision < time.Second {
+		precision *= 10
+	}
+	return precision
+}
@@ -0,0 +1,58 @@
+package report
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var concurrencySamples = []ConcurrencySample{
+	{Offset: 0, Limit: 1},
+	{Offset: time.Second, Limit: 2},
+	{Offset: 2 * time.Second, Limit: 4},
+	{Offset: 3 * time.Second, Limit: 8},
+	{Offset: 5 * time.Second, Limit: 7},
+	{Offset: 7 * time.Second, Limit: 6},
+	{Offset: 9 * time.Second, Limit: 7},
+}
+
+func TestBuildConcurrencyTimeline(t *testing.T) {
+	assert := assert.New(t)
+
+	timeline := BuildConcurrencyTimeline(concurrencySamples, 10*time.Second, 20, 5)
+
+	assert.Equal([]ConcurrencyPeriod{
+		{Start: 0, End: 2 * time.Second, Average: 1.5},
+		{Start: 2 * time.Second, End: 4 * time.Second, Average: 6},
+		{Start: 4 * time.Second, End: 6 * time.Second, Average: 7.5},
+		{Start: 6 * time.Second, End: 8 * time.Second, Average: 6.5},
+		{Start: 8 * time.Second, End: 10 * time.Second, Average: 6.5},
+	}, timeline.Periods)
+	assert.Equal(6.6, timeline.Equilibrium)
+	assert.Equal(6, timeline.SettledMin)
+	assert.Equal(7, timeline.SettledMax)
+}
+
+func TestBuildConcurrencyTimeline_Empty(t *testing.T) {
+	timeline := BuildConcurrencyTimeline(nil, 10*time.Second, 20, 5)
+
+	assert.Empty(t, timeline.Periods)
+}
+
+func TestConcurrencyTimeline_String(t *testing.T) {
+	timeline := BuildConcurrencyTimeline(concurrencySamples, 10*time.Second, 20, 5)
+
+	assert.Equal(t, `0s - 2s:           =>                    1.5
+2s - 4s:           ======>               6.0
+4s - 6s:           =======>              7.5
+6s - 8s:           ======>               6.5
+8s - 10s:          ======>               6.5
+Equilibrium: 6.6 concurrent requests (6 - 7 over the second half of the test, limit 20)
+`, timeline.String())
+}
+
+func TestLabelPrecision(t *testing.T) {
+	assert.Equal(t, time.Millisecond, labelPrecision(5*time.Millisecond))
+	assert.Equal(t, 100*time.Millisecond, labelPrecision(300*time.Millisecond))
+	assert.Equal(t, time.Second, labelPrecision(90*time.Second))
+}
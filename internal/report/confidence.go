@@ -0,0 +1,122 @@
+package report
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/montanaflynn/stats"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// bootstrapResamples is how many times the latencies are resampled to estimate each confidence interval
+const bootstrapResamples = 500
+
+// ConfidenceInterval is where a statistic is 95% likely to lie, from bootstrap resampling
+type ConfidenceInterval struct {
+	Low  time.Duration
+	High time.Duration
+}
+
+func (c ConfidenceInterval) Width() time.Duration {
+	return c.High - c.Low
+}
+
+// Format shows the interval at the timing resolution it was truncated to
+func (c ConfidenceInterval) Format(resolution time.Duration) string {
+	return fmt.Sprintf("%s - %s", FormatLatency(c.Low, resolution), FormatLatency(c.High, resolution))
+}
+
+// ConfidenceIntervals shows how far the reported percentiles and mean could move with more requests
+type ConfidenceIntervals struct {
+	Percentiles []float64
+	Data        map[float64]ConfidenceInterval
+	Mean        ConfidenceInterval
+	Resolution  time.Duration
+}
+
+func BuildConfidenceIntervals(timings []*responseTimings.Timing, percentiles []float64, resolution time.Duration) (intervals ConfidenceIntervals) {
+	if len(percentiles) == 0 {
+		percentiles = DefaultPercentiles
+	}
+	resolution = orDefaultResolution(resolution)
+	intervals = ConfidenceIntervals{Percentiles: percentiles, Data: make(map[float64]ConfidenceInterval), Resolution: resolution}
+	durations := make([]float64, len(timings))
+	for i, timing := range timings {
+		durations[i] = float64(timing.TotalDuration())
+	}
+	if len(durations) == 0 {
+		return
+	}
+	sort.Float64s(durations)
+
+	// the statistics of each resample, with the mean after the percentiles
+	estimates := make([][]float64, len(percentiles)+1)
+	// seeded, so the same run always reports the same intervals
+	random := rand.New(rand.NewSource(1))
+	picks := make([]int, len(durations))
+	resample := make([]float64, 0, len(durations))
+	for i := 0; i < bootstrapResamples; i++ {
+		// count how often each duration is picked, so the resample comes out sorted without sorting it
+		for j := range picks {
+			picks[j] = 0
+		}
+		for range durations {
+			picks[random.Intn(len(durations))]++
+		}
+		resample = resample[:0]
+		for j, count := range picks {
+			for ; count > 0; count-- {
+				resample = append(resample, durations[j])
+			}
+		}
+		for j, percentile := range percentiles {
+			estimates[j] = append(estimates[j], sortedPercentile(resample, percentile))
+		}
+		mean, _ := stats.Mean(resample)
+		estimates[len(percentiles)] = append(estimates[len(percentiles)], mean)
+	}
+
+	for i, percentile := range percentiles {
+		intervals.Data[percentile] = interval(estimates[i], resolution)
+	}
+	intervals.Mean = interval(estimates[len(percentiles)], resolution)
+	return
+}
+
+// PercentileInterval is the confidence interval of a single percentile
+func PercentileInterval(timings []*responseTimings.Timing, percentile float64, resolution time.Duration) ConfidenceInterval {
+	return BuildConfidenceIntervals(timings, []float64{percentile}, resolution).Data[percentile]
+}
+
+// sortedPercentile matches stats.Percentile for already sorted input, without copying and sorting it again,
+// which is most of the cost of resampling a long run
+func sortedPercentile(sorted []float64, percentile float64) float64 {
+	index := percentile / 100 * float64(len(sorted))
+	i := int(index)
+	switch {
+	case len(sorted) == 1 || i < 1:
+		return sorted[0]
+	case index == float64(i):
+		return sorted[i-1]
+	}
+	return (sorted[i-1] + sorted[i]) / 2
+}
+
+// interval takes the middle 95% of the resampled estimates
+func interval(estimates []float64, resolution time.Duration) ConfidenceInterval {
+	return ConfidenceInterval{
+		Low:  statistic(stats.Percentile(estimates, 2.5)).Truncate(resolution),
+		High: statistic(stats.Percentile(estimates, 97.5)).Truncate(resolution),
+	}
+}
+
+func (c ConfidenceIntervals) String() string {
+	lines := make([]string, 0, len(c.Percentiles)+1)
+	for _, percentile := range c.Percentiles {
+		lines = append(lines, fmt.Sprintf("%s: %s", FormatPercentile(percentile), c.Data[percentile].Format(c.Resolution)))
+	}
+	lines = append(lines, fmt.Sprintf("Mean: %s", c.Mean.Format(c.Resolution)))
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,74 @@
+package report
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/montanaflynn/stats"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func latencyTimings(latencies ...time.Duration) (timings []*responseTimings.Timing) {
+	for _, latency := range latencies {
+		timings = append(timings, &responseTimings.Timing{GotConn: time.Unix(0, 0), Done: time.Unix(0, 0).Add(latency)})
+	}
+	return
+}
+
+func TestBuildConfidenceIntervals(t *testing.T) {
+	assert := assert.New(t)
+	var latencies []time.Duration
+	for i := 1; i <= 200; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	timings := latencyTimings(latencies...)
+
+	intervals := BuildConfidenceIntervals(timings, []float64{50, 99}, time.Millisecond)
+
+	assert.Equal([]float64{50, 99}, intervals.Percentiles)
+	for _, interval := range []ConfidenceInterval{intervals.Data[50], intervals.Mean} {
+		assert.True(interval.Low < 100*time.Millisecond && interval.High > 100*time.Millisecond, interval.Format(time.Millisecond))
+		assert.True(interval.Width() < 40*time.Millisecond, interval.Format(time.Millisecond))
+	}
+	assert.True(intervals.Data[99].Low <= 198*time.Millisecond && intervals.Data[99].High <= 200*time.Millisecond)
+	assert.Equal(intervals, BuildConfidenceIntervals(timings, []float64{50, 99}, time.Millisecond), "intervals are reproducible")
+	assert.Equal(intervals.Data[99], PercentileInterval(timings, 99, time.Millisecond))
+}
+
+func TestBuildConfidenceIntervals_Constant(t *testing.T) {
+	intervals := BuildConfidenceIntervals(latencyTimings(5*time.Millisecond, 5*time.Millisecond), nil, time.Millisecond)
+
+	assert.Equal(t, DefaultPercentiles, intervals.Percentiles)
+	assert.Equal(t, ConfidenceInterval{Low: 5 * time.Millisecond, High: 5 * time.Millisecond}, intervals.Mean)
+	assert.Equal(t, time.Duration(0), intervals.Data[99].Width())
+}
+
+func TestBuildConfidenceIntervals_Empty(t *testing.T) {
+	intervals := BuildConfidenceIntervals(nil, []float64{99}, time.Millisecond)
+
+	assert.Equal(t, ConfidenceInterval{}, intervals.Data[99])
+	assert.Equal(t, "99th: 0ms - 0ms\nMean: 0ms - 0ms\n", intervals.String())
+}
+
+func TestConfidenceIntervals_String(t *testing.T) {
+	intervals := ConfidenceIntervals{
+		Percentiles: []float64{50, 99.9},
+		Data: map[float64]ConfidenceInterval{
+			50:   {Low: 88 * time.Millisecond, High: 92 * time.Millisecond},
+			99.9: {Low: 171 * time.Millisecond, High: 239 * time.Millisecond},
+		},
+		Mean: ConfidenceInterval{Low: 89 * time.Millisecond, High: 99 * time.Millisecond},
+	}
+
+	assert.Equal(t, "50th: 88ms - 92ms\n99.9th: 171ms - 239ms\nMean: 89ms - 99ms\n", intervals.String())
+}
+
+func TestSortedPercentile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 5, 8, 13, 21}
+	for _, percentile := range []float64{20, 50, 66, 99, 99.9, 100} {
+		expected, _ := stats.Percentile(sorted, percentile)
+
+		assert.Equal(t, expected, sortedPercentile(sorted, percentile), percentile)
+	}
+	assert.Equal(t, 4.0, sortedPercentile([]float64{4}, 50))
+}
@@ -0,0 +1,74 @@
+package report
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"sort"
+	"strings"
+)
+
+type ConnectionSummary struct {
+	TotalCount  int
+	ReusedCount int
+	Protocols   map[string]int
+	Tls         map[string]int
+}
+
+func BuildConnectionSummary(timings []*responseTimings.Timing) (summary ConnectionSummary) {
+	summary = ConnectionSummary{Protocols: make(map[string]int), Tls: make(map[string]int)}
+	for _, timing := range timings {
+		if timing == nil {
+			continue
+		}
+		connection := timing.Connection
+		summary.TotalCount++
+		if connection.Reused {
+			summary.ReusedCount++
+		}
+		if connection.Protocol != "" {
+			summary.Protocols[connection.Protocol]++
+		}
+		if connection.TlsVersion != "" {
+			summary.Tls[strings.TrimSpace(connection.TlsVersion+" "+connection.TlsCipherSuite)]++
+		}
+	}
+	return
+}
+
+func (c ConnectionSummary) ReuseRatio() float64 {
+	if c.TotalCount == 0 {
+		return 0
+	}
+	return float64(c.ReusedCount) / float64(c.TotalCount)
+}
+
+func (c ConnectionSummary) String() (string string) {
+	string = fmt.Sprintf("Reused: %d of %d (%.0f%%)\n", c.ReusedCount, c.TotalCount, c.ReuseRatio()*100)
+	if len(c.Protocols) > 0 {
+		string += "Protocols:\n" + bars(c.Protocols, c.TotalCount)
+	}
+	if len(c.Tls) > 0 {
+		string += "TLS:\n" + bars(c.Tls, c.TotalCount)
+	}
+	return
+}
+
+// bars renders one line per key, sorted by name, with a bar showing its share of total.
+func bars(counts map[string]int, total int) (result string) {
+	keys := make([]string, 0, len(counts))
+	width := 0
+	for key := range counts {
+		keys = append(keys, key)
+		if len(key) > width {
+			width = len(key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		count := counts[key]
+		percentage := float32(count) / float32(total)
+		bar := strings.Repeat("=", int(percentage*20)) + ">"
+		result += fmt.Sprintf("%-*s %-21s %dx\n", width+1, key+":", bar, count)
+	}
+	return
+}
@@ -0,0 +1,49 @@
+package report
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuildConnectionSummary(t *testing.T) {
+	tls13 := responseTimings.Connection{Protocol: "HTTP/2.0", TlsVersion: "TLS 1.3", TlsCipherSuite: "TLS_AES_128_GCM_SHA256"}
+	reusedTls13 := tls13
+	reusedTls13.Reused = true
+	timings := []*responseTimings.Timing{
+		{Connection: tls13},
+		{Connection: reusedTls13},
+		{Connection: reusedTls13},
+		{Connection: responseTimings.Connection{Protocol: "HTTP/1.1"}},
+		nil,
+	}
+
+	summary := BuildConnectionSummary(timings)
+
+	assert.Equal(t, ConnectionSummary{
+		TotalCount:  4,
+		ReusedCount: 2,
+		Protocols:   map[string]int{"HTTP/2.0": 3, "HTTP/1.1": 1},
+		Tls:         map[string]int{"TLS 1.3 TLS_AES_128_GCM_SHA256": 3},
+	}, summary)
+	assert.Equal(t, 0.5, summary.ReuseRatio())
+}
+
+func TestConnectionSummary_String(t *testing.T) {
+	summary := ConnectionSummary{
+		TotalCount:  4,
+		ReusedCount: 2,
+		Protocols:   map[string]int{"HTTP/2.0": 3, "HTTP/1.1": 1},
+		Tls:         map[string]int{"TLS 1.3 TLS_AES_128_GCM_SHA256": 3},
+	}
+
+	assert.Equal(t, `Reused: 2 of 4 (50%)
+Protocols:
+HTTP/1.1: =====>                1x
+HTTP/2.0: ===============>      3x
+TLS:
+TLS 1.3 TLS_AES_128_GCM_SHA256: ===============>      3x
+`, summary.String())
+
+	assert.Equal(t, "Reused: 0 of 0 (0%)\n", ConnectionSummary{}.String())
+}
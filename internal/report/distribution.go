@@ -0,0 +1,185 @@
+package report
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"strings"
+	"time"
+)
+
+// heatmapShades go from an empty cell to the busiest one
+const heatmapShades = " .:-=+*#%@"
+
+// LatencyBucket counts the latencies from Min up to, but not including, Max
+type LatencyBucket struct {
+	Min   time.Duration
+	Max   time.Duration
+	Count int
+}
+
+// LatencyDistribution buckets latencies on a log scale, so both fast and slow modes show up
+type LatencyDistribution struct {
+	Buckets    []LatencyBucket // from the fastest non-empty bucket to the slowest, including empty ones between
+	TotalCount int
+	Resolution time.Duration // the smallest bucket's width
+}
+
+// bucketEdges are 1, 2 and 5 times each power of ten of the timing resolution, up to past the longest latency
+func bucketEdges(longest time.Duration, resolution time.Duration) []time.Duration {
+	edges := []time.Duration{0}
+	for scale := resolution; edges[len(edges)-1] <= longest; scale *= 10 {
+		edges = append(edges, scale, 2*scale, 5*scale)
+	}
+	return edges
+}
+
+func bucketIndex(edges []time.Duration, latency time.Duration) int {
+	index := 0
+	for index < len(edges)-2 && latency >= edges[index+1] {
+		index++
+	}
+	return index
+}
+
+func BuildLatencyDistribution(timings []*responseTimings.Timing, resolution time.Duration) (distribution LatencyDistribution) {
+	distribution.Resolution = orDefaultResolution(resolution)
+	var latencies []time.Duration
+	var longest time.Duration
+	for _, timing := range timings {
+		if timing == nil {
+			continue
+		}
+		latency := timing.TotalDuration()
+		latencies = append(latencies, latency)
+		if latency > longest {
+			longest = latency
+		}
+	}
+	if len(latencies) == 0 {
+		return
+	}
+	edges := bucketEdges(longest, distribution.Resolution)
+	counts := make([]int, len(edges)-1)
+	first, last := len(counts)-1, 0
+	for _, latency := range latencies {
+		index := bucketIndex(edges, latency)
+		counts[index]++
+		if index < first {
+			first = index
+		}
+		if index > last {
+			last = index
+		}
+	}
+	for index := first; index <= last; index++ {
+		distribution.Buckets = append(distribution.Buckets, LatencyBucket{Min: edges[index], Max: edges[index+1], Count: counts[index]})
+	}
+	distribution.TotalCount = len(latencies)
+	return
+}
+
+// String draws a bar for each bucket, scaled to the largest bucket so the shape is visible
+func (d LatencyDistribution) String() (result string) {
+	largest := 0
+	for _, bucket := range d.Buckets {
+		if bucket.Count > largest {
+			largest = bucket.Count
+		}
+	}
+	for _, bucket := range d.Buckets {
+		bar := ""
+		if bucket.Count > 0 {
+			bar = strings.Repeat("=", bucket.Count*20/largest) + ">"
+		}
+		label := fmt.Sprintf("%s - %s:", FormatLatency(bucket.Min, d.Resolution), FormatLatency(bucket.Max, d.Resolution))
+		result += fmt.Sprintf("%-22s %-21s %dx (%.1f%%)\n", label, bar, bucket.Count, float64(bucket.Count)/float64(d.TotalCount)*100)
+	}
+	return
+}
+
+// LatencyHeatmap counts latencies by bucket over the course of a run, to show when slow requests happened
+type LatencyHeatmap struct {
+	Buckets    []LatencyBucket // rows, fastest first
+	Cells      [][]int         // counts for each row, by column
+	ColumnTime time.Duration   // length of time each column covers
+	Resolution time.Duration
+}
+
+func BuildLatencyHeatmap(timings []*responseTimings.Timing, columns int, resolution time.Duration) (heatmap LatencyHeatmap) {
+	distribution := BuildLatencyDistribution(timings, resolution)
+	heatmap.Resolution = distribution.Resolution
+	if len(distribution.Buckets) == 0 || columns < 1 {
+		return
+	}
+	var first, last time.Time
+	for _, timing := range timings {
+		if timing == nil {
+			continue
+		}
+		start := requestStart(timing)
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+	}
+	// round up, so the last request falls in the last column
+	heatmap.ColumnTime = last.Sub(first)/time.Duration(columns) + 1
+	heatmap.Buckets = distribution.Buckets
+	heatmap.Cells = make([][]int, len(heatmap.Buckets))
+	for row := range heatmap.Cells {
+		heatmap.Cells[row] = make([]int, columns)
+	}
+
+	edges := []time.Duration{distribution.Buckets[0].Min}
+	for _, bucket := range distribution.Buckets {
+		edges = append(edges, bucket.Max)
+	}
+	for _, timing := range timings {
+		if timing == nil {
+			continue
+		}
+		row := bucketIndex(edges, timing.TotalDuration())
+		column := int(requestStart(timing).Sub(first) / heatmap.ColumnTime)
+		heatmap.Cells[row][column]++
+	}
+	return
+}
+
+// requestStart falls back to when the connection was made, for run files recorded before requests had a start time
+func requestStart(timing *responseTimings.Timing) time.Time {
+	if !timing.Start.IsZero() {
+		return timing.Start
+	}
+	return timing.StartTime()
+}
+
+// String draws the slowest bucket at the top, with each cell shaded by its count relative to the busiest cell
+func (h LatencyHeatmap) String() (result string) {
+	busiest := 0
+	for _, row := range h.Cells {
+		for _, count := range row {
+			if count > busiest {
+				busiest = count
+			}
+		}
+	}
+	for row := len(h.Buckets) - 1; row >= 0; row-- {
+		cells := make([]byte, len(h.Cells[row]))
+		for column, count := range h.Cells[row] {
+			shade := count * (len(heatmapShades) - 1) / busiest
+			if shade == 0 && count > 0 {
+				shade = 1
+			}
+			cells[column] = heatmapShades[shade]
+		}
+		result += fmt.Sprintf("%10s |%s|\n", FormatLatency(h.Buckets[row].Min, h.Resolution), cells)
+	}
+	if len(h.Buckets) > 0 {
+		width := len(h.Cells[0])
+		end := (h.ColumnTime * time.Duration(width)).Truncate(h.Resolution)
+		result += fmt.Sprintf("%10s  %-*s%s\n", "", width-len(end.String())+1, "0s", end)
+	}
+	return
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func distributionTiming(start time.Duration, latency time.Duration) *responseTimings.Timing {
+	return &responseTimings.Timing{
+		Start:   time.Unix(0, 0).Add(start),
+		GotConn: time.Unix(0, 0).Add(start),
+		Done:    time.Unix(0, 0).Add(start + latency),
+	}
+}
+
+func TestBuildLatencyDistribution(t *testing.T) {
+	assert := assert.New(t)
+	timings := []*responseTimings.Timing{
+		distributionTiming(0, 3*time.Millisecond),
+		distributionTiming(0, 4*time.Millisecond),
+		distributionTiming(0, 3*time.Millisecond),
+		distributionTiming(0, 150*time.Millisecond),
+		nil,
+	}
+
+	distribution := BuildLatencyDistribution(timings, time.Millisecond)
+
+	assert.Equal(4, distribution.TotalCount)
+	assert.Equal([]LatencyBucket{
+		{Min: 2 * time.Millisecond, Max: 5 * time.Millisecond, Count: 3},
+		{Min: 5 * time.Millisecond, Max: 10 * time.Millisecond},
+		{Min: 10 * time.Millisecond, Max: 20 * time.Millisecond},
+		{Min: 20 * time.Millisecond, Max: 50 * time.Millisecond},
+		{Min: 50 * time.Millisecond, Max: 100 * time.Millisecond},
+		{Min: 100 * time.Millisecond, Max: 200 * time.Millisecond, Count: 1},
+	}, distribution.Buckets)
+	assert.Equal(`2ms - 5ms:             ====================> 3x (75.0%)
+5ms - 10ms:                                  0x (0.0%)
+10ms - 20ms:                                 0x (0.0%)
+20ms - 50ms:                                 0x (0.0%)
+50ms - 100ms:                                0x (0.0%)
+100ms - 200ms:         ======>               1x (25.0%)
+`, distribution.String())
+}
+
+func TestBuildLatencyDistribution_Empty(t *testing.T) {
+	distribution := BuildLatencyDistribution(nil, time.Millisecond)
+
+	assert.Empty(t, distribution.Buckets)
+	assert.Equal(t, "", distribution.String())
+}
+
+func TestBuildLatencyDistribution_SubMillisecond(t *testing.T) {
+	timing := distributionTiming(0, 300*time.Microsecond)
+	timing.SetResolution(time.Microsecond)
+
+	distribution := BuildLatencyDistribution([]*responseTimings.Timing{timing}, time.Microsecond)
+
+	assert.Equal(t, []LatencyBucket{{Min: 200 * time.Microsecond, Max: 500 * time.Microsecond, Count: 1}}, distribution.Buckets)
+}
+
+func TestBuildLatencyHeatmap(t *testing.T) {
+	assert := assert.New(t)
+	timings := []*responseTimings.Timing{
+		distributionTiming(0, 3*time.Millisecond),
+		distributionTiming(1500*time.Millisecond, 3*time.Millisecond),
+		distributionTiming(1500*time.Millisecond, 3*time.Millisecond),
+		distributionTiming(2500*time.Millisecond, 15*time.Millisecond),
+		distributionTiming(4*time.Second, 3*time.Millisecond),
+	}
+
+	heatmap := BuildLatencyHeatmap(timings, 4, time.Millisecond)
+
+	assert.Equal(time.Second+1, heatmap.ColumnTime)
+	assert.Equal([][]int{{1, 2, 0, 1}, {0, 0, 0, 0}, {0, 0, 1, 0}}, heatmap.Cells)
+	assert.Equal(`      10ms |  = |
+       5ms |    |
+       2ms |=@ =|
+            0s 4s
+`, heatmap.String())
+}
@@ -0,0 +1,130 @@
+package report
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	GroupByHeader = "header"
+	GroupByPath   = "path"
+	GroupByStatus = "status"
+)
+
+// noGroup is used for responses without a value to group them by, like a missing header
+const noGroup = "(none)"
+
+// Grouper picks the group each response belongs to, by a response header, the request path or the status class
+type Grouper struct {
+	Key    string // as given, e.g. header:X-Served-By
+	kind   string
+	header string
+}
+
+func NewGrouper(key string) (Grouper, error) {
+	kind, header, hasHeader := strings.Cut(key, ":")
+	switch {
+	case kind == GroupByHeader && hasHeader && header != "":
+		return Grouper{Key: key, kind: kind, header: http.CanonicalHeaderKey(header)}, nil
+	case (kind == GroupByPath || kind == GroupByStatus) && !hasHeader:
+		return Grouper{Key: key, kind: kind}, nil
+	}
+	return Grouper{}, fmt.Errorf("invalid groupby %q - valid options are header:<name>, path and status", key)
+}
+
+// NeedsHeaders is true when responses must keep their headers to be grouped
+func (g Grouper) NeedsHeaders() bool {
+	return g.kind == GroupByHeader
+}
+
+func (g Grouper) Group(responseTiming responseTimings.ResponseTiming) string {
+	switch g.kind {
+	case GroupByHeader:
+		if responseTiming.Response != nil {
+			if value := responseTiming.Response.Header.HttpHeader.Get(g.header); value != "" {
+				return value
+			}
+		}
+	case GroupByPath:
+		if responseTiming.Request != nil {
+			if requestUrl, err := url.Parse(responseTiming.Request.Url); err == nil {
+				if requestUrl.Path == "" {
+					return "/"
+				}
+				return requestUrl.Path
+			}
+		}
+	case GroupByStatus:
+		if responseTiming.Response != nil && responseTiming.Response.StatusCode >= 100 {
+			return fmt.Sprintf("%dxx", responseTiming.Response.StatusCode/100)
+		}
+		return "failed"
+	}
+	return noGroup
+}
+
+// Filter keeps the responses in the given group
+func (g Grouper) Filter(timings responseTimings.ResponseTimings, group string) (filtered responseTimings.ResponseTimings) {
+	for _, responseTiming := range timings {
+		if g.Group(responseTiming) == group {
+			filtered = append(filtered, responseTiming)
+		}
+	}
+	return
+}
+
+// Groups breaks statuses and latency down by group, to find e.g. one slow instance behind a load balancer
+type Groups struct {
+	Key         string
+	Counts      map[string]int
+	Statuses    map[string]StatusHistogram
+	Percentiles map[string]LatencyPercentiles
+}
+
+func BuildGroups(grouper Grouper, timings responseTimings.ResponseTimings, percentiles []float64, resolution time.Duration) (groups Groups) {
+	groups = Groups{
+		Key:         grouper.Key,
+		Counts:      make(map[string]int),
+		Statuses:    make(map[string]StatusHistogram),
+		Percentiles: make(map[string]LatencyPercentiles),
+	}
+	grouped := make(map[string]responseTimings.ResponseTimings)
+	for _, responseTiming := range timings {
+		group := grouper.Group(responseTiming)
+		grouped[group] = append(grouped[group], responseTiming)
+	}
+	for group, groupTimings := range grouped {
+		groups.Counts[group] = len(groupTimings)
+		groups.Statuses[group] = BuildStatusHistogram(groupTimings.Responses(), len(groupTimings))
+		groups.Percentiles[group] = BuildLatencyPercentiles(groupTimings.Timings(), percentiles, resolution)
+	}
+	return
+}
+
+func (g Groups) String() (result string) {
+	groupNames := make([]string, 0, len(g.Counts))
+	width := 0
+	for group := range g.Counts {
+		groupNames = append(groupNames, group)
+		if len(group) > width {
+			width = len(group)
+		}
+	}
+	sort.Strings(groupNames)
+	for _, group := range groupNames {
+		statuses := g.Statuses[group]
+		sort.Ints(statuses.keys)
+		statusCounts := make([]string, len(statuses.keys))
+		for i, statusCode := range statuses.keys {
+			statusCounts[i] = fmt.Sprintf("%d: %dx", statusCode, statuses.Data[statusCode])
+		}
+		result += fmt.Sprintf("%-*s %dx  %s\n%-*s %s\n", width+1, group+":", g.Counts[group], strings.Join(statusCounts, "  "),
+			width+1, "", g.Percentiles[group].Summary())
+	}
+	return
+}
@@ -0,0 +1,76 @@
+package report
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func groupedResponse(instance string, url string, statusCode int, latency time.Duration) responseTimings.ResponseTiming {
+	header := http.Header{}
+	if instance != "" {
+		header.Set("X-Served-By", instance)
+	}
+	return responseTimings.ResponseTiming{
+		Request:  &responseTimings.Request{Url: url},
+		Response: &responseTimings.Response{StatusCode: statusCode, Header: responseTimings.Header{HttpHeader: header}},
+		Timing:   &responseTimings.Timing{GotConn: time.Unix(0, 0), Done: time.Unix(0, 0).Add(latency)},
+	}
+}
+
+func TestNewGrouper(t *testing.T) {
+	assert := assert.New(t)
+	for _, key := range []string{"header:X-Served-By", "path", "status"} {
+		grouper, err := NewGrouper(key)
+
+		assert.Nil(err)
+		assert.Equal(key, grouper.Key)
+	}
+	for _, key := range []string{"", "header", "header:", "path:/", "host"} {
+		_, err := NewGrouper(key)
+
+		assert.EqualError(err, `invalid groupby "`+key+`" - valid options are header:<name>, path and status`)
+	}
+}
+
+func TestGrouper_Group(t *testing.T) {
+	assert := assert.New(t)
+	header, _ := NewGrouper("header:x-served-by")
+	path, _ := NewGrouper("path")
+	status, _ := NewGrouper("status")
+	response := groupedResponse("web-1", "https://example.com/api?page=2", 503, 0)
+	missing := groupedResponse("", "https://example.com", 0, 0)
+
+	assert.True(header.NeedsHeaders())
+	assert.False(path.NeedsHeaders())
+	assert.Equal("web-1", header.Group(response))
+	assert.Equal("(none)", header.Group(missing))
+	assert.Equal("/api", path.Group(response))
+	assert.Equal("/", path.Group(missing))
+	assert.Equal("5xx", status.Group(response))
+	assert.Equal("failed", status.Group(missing))
+}
+
+func TestBuildGroups(t *testing.T) {
+	assert := assert.New(t)
+	grouper, _ := NewGrouper("header:X-Served-By")
+	timings := responseTimings.ResponseTimings{
+		groupedResponse("web-1", "", 200, 10*time.Millisecond),
+		groupedResponse("web-2", "", 200, 20*time.Millisecond),
+		groupedResponse("web-2", "", 503, 300*time.Millisecond),
+		groupedResponse("web-1", "", 200, 20*time.Millisecond),
+	}
+
+	groups := BuildGroups(grouper, timings, nil, time.Millisecond)
+
+	assert.Equal(map[string]int{"web-1": 2, "web-2": 2}, groups.Counts)
+	assert.Equal(map[int]int{200: 1, 503: 1}, groups.Statuses["web-2"].Data)
+	assert.Equal(300*time.Millisecond, groups.Percentiles["web-2"].Max)
+	assert.Equal(`web-1: 2x  200: 2x
+       50th: 10ms  95th: 15ms  99th: 15ms  100th: 20ms
+web-2: 2x  200: 1x  503: 1x
+       50th: 20ms  95th: 160ms  99th: 160ms  100th: 300ms
+`, groups.String())
+}
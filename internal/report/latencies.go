@@ -4,35 +4,99 @@ import (
 	"fmt"
 	"github.com/JamesBalazs/lode/internal/responseTimings"
 	"github.com/montanaflynn/stats"
-	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
-var latencyPercentiles = []float64{50, 66, 75, 80, 90, 95, 98, 99, 100}
+// DefaultPercentiles are reported when no percentiles are chosen
+var DefaultPercentiles = []float64{50, 66, 75, 80, 90, 95, 98, 99, 100}
+
+// summaryPercentiles are shown when latency is broken down further, e.g. by source address
+var summaryPercentiles = []float64{50, 95, 99, 100}
 
 type LatencyPercentiles struct {
-	Data map[int]int
+	Percentiles []float64                 // in the order they are shown
+	Data        map[float64]time.Duration // for each of Percentiles, and the summary percentiles
+	Min         time.Duration
+	Max         time.Duration
+	Mean        time.Duration
+	StdDev      time.Duration
+	Mad         time.Duration // median absolute deviation
+	Resolution  time.Duration // the timing resolution they were truncated to
 }
 
-func BuildLatencyPercentiles(timings []*responseTimings.Timing) (histogram LatencyPercentiles) {
-	histogram = LatencyPercentiles{Data: make(map[int]int)}
+func BuildLatencyPercentiles(timings []*responseTimings.Timing, percentiles []float64, resolution time.Duration) LatencyPercentiles {
 	timingsCount := len(timings)
 	durations := make([]float64, timingsCount)
 	for i, timing := range timings {
-		durations[i] = float64(timing.TotalDuration().Milliseconds())
+		durations[i] = float64(timing.TotalDuration())
 	}
+	return buildPercentiles(durations, percentiles, resolution)
+}
 
-	for _, percentile := range latencyPercentiles {
-		percentileLatency, _ := stats.Percentile(durations, percentile)
-		histogram.Data[int(percentile)] = int(percentileLatency)
+// buildPercentiles summarises durations given in nanoseconds, using the default percentiles if none are given
+func buildPercentiles(durations []float64, percentiles []float64, resolution time.Duration) (latencies LatencyPercentiles) {
+	if len(percentiles) == 0 {
+		percentiles = DefaultPercentiles
 	}
-
+	resolution = orDefaultResolution(resolution)
+	latencies = LatencyPercentiles{Percentiles: percentiles, Data: make(map[float64]time.Duration), Resolution: resolution}
+	for _, percentile := range append(append([]float64{}, percentiles...), summaryPercentiles...) {
+		latencies.Data[percentile] = statistic(stats.Percentile(durations, percentile)).Truncate(resolution)
+	}
+	latencies.Min = statistic(stats.Min(durations)).Truncate(resolution)
+	latencies.Max = statistic(stats.Max(durations)).Truncate(resolution)
+	latencies.Mean = statistic(stats.Mean(durations)).Truncate(resolution)
+	latencies.StdDev = statistic(stats.StandardDeviation(durations)).Truncate(resolution)
+	latencies.Mad = statistic(stats.MedianAbsoluteDeviation(durations)).Truncate(resolution)
 	return
 }
 
+// statistic turns a statistic of durations back into a duration, or zero if there were no durations
+func statistic(value float64, err error) time.Duration {
+	if err != nil {
+		return 0
+	}
+	return time.Duration(value)
+}
+
+// orDefaultResolution treats an unset resolution as the default, milliseconds
+func orDefaultResolution(resolution time.Duration) time.Duration {
+	if resolution <= 0 {
+		return responseTimings.DefaultResolution
+	}
+	return resolution
+}
+
 func (t LatencyPercentiles) String() (string string) {
-	sort.Float64s(latencyPercentiles)
-	for _, percentile := range latencyPercentiles {
-		string = string + fmt.Sprintf("%dth: %dms\n", int(percentile), t.Data[int(percentile)])
+	for _, percentile := range t.Percentiles {
+		string = string + fmt.Sprintf("%s: %s\n", FormatPercentile(percentile), FormatLatency(t.Data[percentile], t.Resolution))
 	}
+	string += fmt.Sprintf("Min: %s  Max: %s  Mean: %s  Std dev: %s  MAD: %s\n",
+		FormatLatency(t.Min, t.Resolution), FormatLatency(t.Max, t.Resolution), FormatLatency(t.Mean, t.Resolution),
+		FormatLatency(t.StdDev, t.Resolution), FormatLatency(t.Mad, t.Resolution))
 	return
 }
+
+// Summary gives the summary percentiles on a single line
+func (t LatencyPercentiles) Summary() string {
+	parts := make([]string, len(summaryPercentiles))
+	for i, percentile := range summaryPercentiles {
+		parts[i] = fmt.Sprintf("%s: %s", FormatPercentile(percentile), FormatLatency(t.Data[percentile], t.Resolution))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// FormatPercentile labels a percentile, e.g. 99th or 99.9th
+func FormatPercentile(percentile float64) string {
+	return strconv.FormatFloat(percentile, 'f', -1, 64) + "th"
+}
+
+// FormatLatency shows whole milliseconds, or fractions of a millisecond at microsecond resolution
+func FormatLatency(latency time.Duration, resolution time.Duration) string {
+	if orDefaultResolution(resolution) < time.Millisecond {
+		return fmt.Sprintf("%.3fms", float64(latency)/float64(time.Millisecond))
+	}
+	return fmt.Sprintf("%dms", latency.Milliseconds())
+}
@@ -15,37 +15,66 @@ func TestBuildLatencyPercentiles(t *testing.T) {
 		{ConnectStart: time.Unix(0, 0), Done: time.Unix(0, 600_000_000)},
 	}
 	expectedHistogram := LatencyPercentiles{
-		Data: map[int]int{
-			50:  500,
-			66:  550,
-			75:  600,
-			80:  650,
-			90:  650,
-			95:  650,
-			98:  650,
-			99:  650,
-			100: 700,
+		Percentiles: DefaultPercentiles,
+		Data: map[float64]time.Duration{
+			50:  500 * time.Millisecond,
+			66:  550 * time.Millisecond,
+			75:  600 * time.Millisecond,
+			80:  650 * time.Millisecond,
+			90:  650 * time.Millisecond,
+			95:  650 * time.Millisecond,
+			98:  650 * time.Millisecond,
+			99:  650 * time.Millisecond,
+			100: 700 * time.Millisecond,
 		},
+		Min:        300 * time.Millisecond,
+		Max:        700 * time.Millisecond,
+		Mean:       525 * time.Millisecond,
+		StdDev:     147 * time.Millisecond,
+		Mad:        100 * time.Millisecond,
+		Resolution: time.Millisecond,
 	}
 
-	histogram := BuildLatencyPercentiles(timings)
+	histogram := BuildLatencyPercentiles(timings, nil, time.Millisecond)
 
 	assert.Equal(t, expectedHistogram, histogram)
 }
 
+func TestBuildLatencyPercentiles_ChosenPercentiles(t *testing.T) {
+	assert := assert.New(t)
+	var timings []*responseTimings.Timing
+	for i := 1; i <= 1000; i++ {
+		timings = append(timings, &responseTimings.Timing{GotConn: time.Unix(0, 0), Done: time.Unix(0, int64(i)*int64(time.Millisecond))})
+	}
+
+	histogram := BuildLatencyPercentiles(timings, []float64{99.9, 50}, time.Millisecond)
+
+	assert.Equal([]float64{99.9, 50}, histogram.Percentiles)
+	assert.Equal(999*time.Millisecond, histogram.Data[99.9])
+	assert.Equal(500*time.Millisecond, histogram.Data[50])
+	assert.Contains(histogram.String(), "99.9th: 999ms\n50th: 500ms\n")
+	assert.Equal(time.Duration(0), BuildLatencyPercentiles(nil, nil, time.Millisecond).Max)
+}
+
 func TestLatencyPercentiles_String(t *testing.T) {
 	histogram := LatencyPercentiles{
-		Data: map[int]int{
-			50:  500,
-			66:  550,
-			75:  600,
-			80:  650,
-			90:  650,
-			95:  650,
-			98:  650,
-			99:  650,
-			100: 700,
+		Percentiles: DefaultPercentiles,
+		Data: map[float64]time.Duration{
+			50:  500 * time.Millisecond,
+			66:  550 * time.Millisecond,
+			75:  600 * time.Millisecond,
+			80:  650 * time.Millisecond,
+			90:  650 * time.Millisecond,
+			95:  650 * time.Millisecond,
+			98:  650 * time.Millisecond,
+			99:  650 * time.Millisecond,
+			100: 700 * time.Millisecond,
 		},
+		Min:    300 * time.Millisecond,
+		Max:    700 * time.Millisecond,
+		Mean:   525 * time.Millisecond,
+		StdDev: 147 * time.Millisecond,
+		Mad:    100 * time.Millisecond,
 	}
 
 	assert.Equal(t,
@@ -58,6 +87,15 @@ func TestLatencyPercentiles_String(t *testing.T) {
 98th: 650ms
 99th: 650ms
 100th: 700ms
+Min: 300ms  Max: 700ms  Mean: 525ms  Std dev: 147ms  MAD: 100ms
 `,
 		histogram.String())
 }
+
+func TestFormatLatency(t *testing.T) {
+	assert.Equal(t, "1234ms", FormatLatency(1234567*time.Microsecond, time.Millisecond))
+	assert.Equal(t, "1234ms", FormatLatency(1234567*time.Microsecond, 0))
+	assert.Equal(t, "1234.567ms", FormatLatency(1234567*time.Microsecond, time.Microsecond))
+	assert.Equal(t, "0.412ms", FormatLatency(412*time.Microsecond, time.Microsecond))
+	assert.Equal(t, "99.99th", FormatPercentile(99.99))
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"time"
+)
+
+type phase struct {
+	name     string
+	happened func(timing *responseTimings.Timing) bool
+	duration func(timing responseTimings.Timing) time.Duration
+}
+
+// phases only count timings where they happened, so e.g. reused connections don't pull the TLS handshake percentiles down
+var phases = []phase{
+	{"DNS Lookup", func(t *responseTimings.Timing) bool { return !t.DnsStart.IsZero() && !t.DnsDone.IsZero() },
+		responseTimings.Timing.DnsLookupDuration},
+	{"TCP Connection", func(t *responseTimings.Timing) bool { return !t.ConnectStart.IsZero() && !t.ConnectDone.IsZero() },
+		responseTimings.Timing.TcpConnectDuration},
+	{"Proxy Tunnel", func(t *responseTimings.Timing) bool { return !t.TunnelStart.IsZero() && !t.TunnelDone.IsZero() },
+		responseTimings.Timing.ProxyTunnelDuration},
+	{"TLS Handshake", func(t *responseTimings.Timing) bool { return !t.TlsStart.IsZero() && !t.TlsDone.IsZero() },
+		responseTimings.Timing.TlsHandshakeDuration},
+	{"Server", func(t *responseTimings.Timing) bool { return !t.GotConn.IsZero() && !t.FirstByte.IsZero() },
+		responseTimings.Timing.ServerDuration},
+	{"Response Transfer", func(t *responseTimings.Timing) bool { return !t.FirstByte.IsZero() && !t.Done.IsZero() },
+		responseTimings.Timing.ResponseTransferDuration},
+}
+
+type PhaseLatency struct {
+	Name        string
+	Count       int
+	Percentiles LatencyPercentiles
+}
+
+// PhasePercentiles breaks latency down by the phase of the request it was spent in
+type PhasePercentiles struct {
+	Phases []PhaseLatency
+}
+
+func BuildPhasePercentiles(timings []*responseTimings.Timing, resolution time.Duration) (percentiles PhasePercentiles) {
+	for _, phase := range phases {
+		var durations []float64
+		for _, timing := range timings {
+			if timing != nil && phase.happened(timing) {
+				durations = append(durations, float64(phase.duration(*timing)))
+			}
+		}
+		percentiles.Phases = append(percentiles.Phases, PhaseLatency{phase.name, len(durations), buildPercentiles(durations, nil, resolution)})
+	}
+	return
+}
+
+func (p PhasePercentiles) String() (result string) {
+	result = fmt.Sprintf("%-17s %6s", "Phase", "Count")
+	for _, percentile := range summaryPercentiles {
+		result += fmt.Sprintf(" %8s", FormatPercentile(percentile))
+	}
+	result += "\n"
+	for _, phase := range p.Phases {
+		if phase.Count == 0 {
+			continue
+		}
+		result += fmt.Sprintf("%-17s %6d", phase.Name, phase.Count)
+		for _, percentile := range summaryPercentiles {
+			result += fmt.Sprintf(" %8s", FormatLatency(phase.Percentiles.Data[percentile], phase.Percentiles.Resolution))
+		}
+		result += "\n"
+	}
+	return
+}
@@ -0,0 +1,40 @@
+package report
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func phaseTiming(tls time.Duration, server time.Duration) *responseTimings.Timing {
+	start := time.Unix(0, 0)
+	timing := &responseTimings.Timing{GotConn: start, FirstByte: start.Add(server), Done: start.Add(server + time.Millisecond)}
+	if tls > 0 {
+		timing.TlsStart, timing.TlsDone = start.Add(-tls), start
+	}
+	return timing
+}
+
+func TestBuildPhasePercentiles(t *testing.T) {
+	assert := assert.New(t)
+	timings := []*responseTimings.Timing{
+		phaseTiming(40*time.Millisecond, 10*time.Millisecond),
+		phaseTiming(0, 20*time.Millisecond),
+		phaseTiming(0, 30*time.Millisecond),
+		nil,
+	}
+
+	percentiles := BuildPhasePercentiles(timings, time.Millisecond)
+
+	counts := map[string]int{}
+	for _, phase := range percentiles.Phases {
+		counts[phase.Name] = phase.Count
+	}
+	assert.Equal(map[string]int{"DNS Lookup": 0, "TCP Connection": 0, "Proxy Tunnel": 0, "TLS Handshake": 1, "Server": 3, "Response Transfer": 3}, counts)
+	assert.Equal(`Phase              Count     50th     95th     99th    100th
+TLS Handshake          1     40ms     40ms     40ms     40ms
+Server                 3     15ms     25ms     25ms     30ms
+Response Transfer      3      1ms      1ms      1ms      1ms
+`, percentiles.String())
+}
@@ -0,0 +1,114 @@
+package report
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"strings"
+	"time"
+)
+
+const waterfallWidth = 30
+
+type RedirectSummary struct {
+	TotalCount      int
+	RedirectedCount int
+	HopCount        int
+	RedirectTime    time.Duration // spent on redirects, across redirected responses
+	TotalTime       time.Duration // including the final hop, across redirected responses
+	Latency         LatencyPercentiles
+}
+
+func BuildRedirectSummary(timings []*responseTimings.Timing, resolution time.Duration) (summary RedirectSummary) {
+	var durations []float64
+	for _, timing := range timings {
+		if timing == nil {
+			continue
+		}
+		summary.TotalCount++
+		if len(timing.Redirects) == 0 {
+			continue
+		}
+		summary.RedirectedCount++
+		summary.HopCount += len(timing.Redirects)
+		summary.RedirectTime += timing.RedirectDuration()
+		summary.TotalTime += timing.TotalDuration()
+		durations = append(durations, float64(timing.RedirectDuration()))
+	}
+	summary.Latency = buildPercentiles(durations, nil, resolution)
+	return
+}
+
+func (r RedirectSummary) String() string {
+	redirected, share := 0.0, 0.0
+	if r.TotalCount > 0 {
+		redirected = float64(r.RedirectedCount) / float64(r.TotalCount) * 100
+	}
+	if r.TotalTime > 0 {
+		share = float64(r.RedirectTime) / float64(r.TotalTime) * 100
+	}
+	return fmt.Sprintf("Redirected: %d of %d (%.0f%%), %d hops\nAdded latency: %s (%.0f%% of their total)\n",
+		r.RedirectedCount, r.TotalCount, redirected, r.HopCount, r.Latency.Summary(), share)
+}
+
+type WaterfallHop struct {
+	Url        string
+	StatusCode int
+	Offset     time.Duration
+	Duration   time.Duration
+}
+
+// RedirectWaterfall lays out each hop of a redirected request one after another, ending with the final response
+type RedirectWaterfall struct {
+	Hops  []WaterfallHop
+	Total time.Duration
+}
+
+func BuildRedirectWaterfall(responseTiming responseTimings.ResponseTiming) (waterfall RedirectWaterfall) {
+	timing := responseTiming.Timing
+	if timing == nil || len(timing.Redirects) == 0 {
+		return
+	}
+	var offset time.Duration
+	for _, redirect := range timing.Redirects {
+		duration := redirect.Timing.TotalDuration()
+		waterfall.Hops = append(waterfall.Hops, WaterfallHop{redirect.Url, redirect.StatusCode, offset, duration})
+		offset += duration
+	}
+	final := WaterfallHop{Offset: offset, Duration: timing.TotalDuration() - offset}
+	if responseTiming.Request != nil {
+		final.Url = responseTiming.Request.Url
+	}
+	if responseTiming.Response != nil {
+		final.StatusCode = responseTiming.Response.StatusCode
+	}
+	waterfall.Hops = append(waterfall.Hops, final)
+	waterfall.Total = timing.TotalDuration()
+	return
+}
+
+func (r RedirectWaterfall) String() (result string) {
+	width := 0
+	for _, hop := range r.Hops {
+		if len(hop.Url) > width {
+			width = len(hop.Url)
+		}
+	}
+	for _, hop := range r.Hops {
+		start, length := 0, waterfallWidth
+		if r.Total > 0 {
+			start = int(hop.Offset * waterfallWidth / r.Total)
+			length = int(hop.Duration * waterfallWidth / r.Total)
+		}
+		if start > waterfallWidth-1 {
+			start = waterfallWidth - 1
+		}
+		if length < 1 {
+			length = 1
+		} else if start+length > waterfallWidth {
+			length = waterfallWidth - start
+		}
+		bar := strings.Repeat(" ", start) + strings.Repeat("=", length) + strings.Repeat(" ", waterfallWidth-start-length)
+		result += fmt.Sprintf("%d %-*s |%s| %s\n", hop.StatusCode, width, hop.Url, bar, hop.Duration)
+	}
+	return
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func redirectedTiming(final time.Duration, hops ...time.Duration) *responseTimings.Timing {
+	timing := sourceTiming("", final)
+	for _, hop := range hops {
+		timing.Redirects = append(timing.Redirects, responseTimings.Redirect{
+			Url:        "http://lode.test/old",
+			StatusCode: 301,
+			Timing:     *sourceTiming("", hop),
+		})
+	}
+	return timing
+}
+
+func TestBuildRedirectSummary(t *testing.T) {
+	assert := assert.New(t)
+	timings := []*responseTimings.Timing{
+		redirectedTiming(30*time.Millisecond, 10*time.Millisecond),
+		redirectedTiming(30*time.Millisecond, 10*time.Millisecond, 10*time.Millisecond),
+		redirectedTiming(30 * time.Millisecond),
+		nil,
+	}
+
+	summary := BuildRedirectSummary(timings, time.Millisecond)
+
+	assert.Equal(3, summary.TotalCount)
+	assert.Equal(2, summary.RedirectedCount)
+	assert.Equal(3, summary.HopCount)
+	assert.Equal(30*time.Millisecond, summary.RedirectTime)
+	assert.Equal(90*time.Millisecond, summary.TotalTime)
+	assert.Equal(`Redirected: 2 of 3 (67%), 3 hops
+Added latency: 50th: 10ms  95th: 15ms  99th: 15ms  100th: 20ms (33% of their total)
+`, summary.String())
+}
+
+func TestBuildRedirectWaterfall(t *testing.T) {
+	assert := assert.New(t)
+	responseTiming := responseTimings.ResponseTiming{
+		Request:  &responseTimings.Request{Url: "https://lode.test/new"},
+		Response: &responseTimings.Response{StatusCode: 200},
+		Timing:   redirectedTiming(20*time.Millisecond, 10*time.Millisecond),
+	}
+
+	waterfall := BuildRedirectWaterfall(responseTiming)
+
+	assert.Equal(RedirectWaterfall{
+		Hops: []WaterfallHop{
+			{Url: "http://lode.test/old", StatusCode: 301, Duration: 10 * time.Millisecond},
+			{Url: "https://lode.test/new", StatusCode: 200, Offset: 10 * time.Millisecond, Duration: 20 * time.Millisecond},
+		},
+		Total: 30 * time.Millisecond,
+	}, waterfall)
+	assert.Equal(`301 http://lode.test/old  |==========                    | 10ms
+200 https://lode.test/new |          ====================| 20ms
+`, waterfall.String())
+
+	responseTiming.Timing = redirectedTiming(20 * time.Millisecond)
+	assert.Empty(BuildRedirectWaterfall(responseTiming).Hops)
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Slo is a target percentage of requests to succeed within a latency, e.g. 99.5% under 400ms
+type Slo struct {
+	Target  float64 // percentage
+	Latency time.Duration
+}
+
+// ParseSlo reads an SLO in the form "99.5% under 400ms", or "99.5%<400ms"
+func ParseSlo(slo string) (Slo, error) {
+	invalid := fmt.Errorf("invalid slo %q - expected a target and latency, e.g. 99.5%% under 400ms", slo)
+	target, latency, found := strings.Cut(slo, " under ")
+	if !found {
+		target, latency, found = strings.Cut(slo, "<")
+	}
+	if !found {
+		return Slo{}, invalid
+	}
+	targetValue, err := strconv.ParseFloat(strings.Trim(target, " %"), 64)
+	if err != nil || targetValue <= 0 || targetValue >= 100 {
+		return Slo{}, invalid
+	}
+	latencyValue, err := time.ParseDuration(strings.TrimSpace(latency))
+	if err != nil || latencyValue <= 0 {
+		return Slo{}, invalid
+	}
+	return Slo{Target: targetValue, Latency: latencyValue}, nil
+}
+
+func (s Slo) String() string {
+	return fmt.Sprintf("%s%% under %s", strconv.FormatFloat(s.Target, 'f', -1, 64), s.Latency)
+}
+
+// SloCompliance counts the requests that met the SLO, by succeeding within its latency
+type SloCompliance struct {
+	Slo        Slo
+	Good       int
+	TotalCount int
+}
+
+func BuildSloCompliance(timings responseTimings.ResponseTimings, slo Slo) (compliance SloCompliance) {
+	compliance = SloCompliance{Slo: slo, TotalCount: len(timings)}
+	for _, responseTiming := range timings {
+		if !failed(responseTiming.Response) && responseTiming.Timing.TotalDuration() < slo.Latency {
+			compliance.Good++
+		}
+	}
+	return
+}
+
+// Compliance is the percentage of requests that met the SLO
+func (s SloCompliance) Compliance() float64 {
+	if s.TotalCount == 0 {
+		return 100
+	}
+	return float64(s.Good) / float64(s.TotalCount) * 100
+}
+
+// BudgetConsumed is the percentage of the error budget, the requests allowed to miss the SLO, that were used
+func (s SloCompliance) BudgetConsumed() float64 {
+	return (100 - s.Compliance()) / (100 - s.Slo.Target) * 100
+}
+
+func (s SloCompliance) Met() bool {
+	return s.BudgetConsumed() <= 100
+}
+
+func (s SloCompliance) String() string {
+	result := "met"
+	if !s.Met() {
+		result = "missed"
+	}
+	return fmt.Sprintf("SLO %s: %.2f%% compliant (%d of %d), %.1f%% of error budget consumed - %s\n",
+		s.Slo, s.Compliance(), s.Good, s.TotalCount, s.BudgetConsumed(), result)
+}
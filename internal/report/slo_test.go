@@ -0,0 +1,46 @@
+package report
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseSlo(t *testing.T) {
+	assert := assert.New(t)
+	for _, slo := range []string{"99.5% under 400ms", "99.5%<400ms", " 99.5 % < 400ms"} {
+		parsed, err := ParseSlo(slo)
+
+		assert.Nil(err)
+		assert.Equal(Slo{Target: 99.5, Latency: 400 * time.Millisecond}, parsed)
+		assert.Equal("99.5% under 400ms", parsed.String())
+	}
+	for _, slo := range []string{"99.5%", "100% under 400ms", "0%<1s", "99% under 0s", "fast<400ms"} {
+		_, err := ParseSlo(slo)
+
+		assert.EqualError(err, `invalid slo "`+slo+`" - expected a target and latency, e.g. 99.5% under 400ms`)
+	}
+}
+
+func TestBuildSloCompliance(t *testing.T) {
+	assert := assert.New(t)
+	timings := responseTimings.ResponseTimings{scoredResponse(503, 10*time.Millisecond)}
+	for i := 0; i < 199; i++ {
+		timings = append(timings, scoredResponse(200, 100*time.Millisecond))
+	}
+
+	compliance := BuildSloCompliance(timings, Slo{Target: 99, Latency: 400 * time.Millisecond})
+
+	assert.Equal(199, compliance.Good)
+	assert.Equal(99.5, compliance.Compliance())
+	assert.InDelta(50, compliance.BudgetConsumed(), 0.0001)
+	assert.True(compliance.Met())
+	assert.Equal("SLO 99% under 400ms: 99.50% compliant (199 of 200), 50.0% of error budget consumed - met\n", compliance.String())
+
+	timings = append(timings, scoredResponse(200, time.Second), scoredResponse(200, time.Second))
+	compliance = BuildSloCompliance(timings, Slo{Target: 99, Latency: 400 * time.Millisecond})
+
+	assert.False(compliance.Met())
+	assert.Contains(compliance.String(), "- missed\n")
+}
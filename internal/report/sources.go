@@ -0,0 +1,51 @@
+package report
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"net"
+	"sort"
+	"time"
+)
+
+// SourceLatencies groups latency percentiles by the local IP address each request was sent from
+type SourceLatencies struct {
+	Counts      map[string]int
+	Percentiles map[string]LatencyPercentiles
+}
+
+func BuildSourceLatencies(timings []*responseTimings.Timing, resolution time.Duration) (sources SourceLatencies) {
+	sources = SourceLatencies{Counts: make(map[string]int), Percentiles: make(map[string]LatencyPercentiles)}
+	grouped := make(map[string][]*responseTimings.Timing)
+	for _, timing := range timings {
+		if timing == nil || timing.Connection.LocalAddr == "" {
+			continue
+		}
+		source := timing.Connection.LocalAddr
+		if host, _, err := net.SplitHostPort(source); err == nil {
+			source = host
+		}
+		grouped[source] = append(grouped[source], timing)
+	}
+	for source, sourceTimings := range grouped {
+		sources.Counts[source] = len(sourceTimings)
+		sources.Percentiles[source] = BuildLatencyPercentiles(sourceTimings, nil, resolution)
+	}
+	return
+}
+
+func (s SourceLatencies) String() (result string) {
+	sourceNames := make([]string, 0, len(s.Counts))
+	width := 0
+	for source := range s.Counts {
+		sourceNames = append(sourceNames, source)
+		if len(source) > width {
+			width = len(source)
+		}
+	}
+	sort.Strings(sourceNames)
+	for _, source := range sourceNames {
+		result += fmt.Sprintf("%-*s %dx  %s\n", width+1, source+":", s.Counts[source], s.Percentiles[source].Summary())
+	}
+	return
+}
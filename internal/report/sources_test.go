@@ -0,0 +1,36 @@
+package report
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func sourceTiming(localAddr string, duration time.Duration) *responseTimings.Timing {
+	return &responseTimings.Timing{
+		GotConn:    time.Unix(0, 0),
+		Done:       time.Unix(0, 0).Add(duration),
+		Connection: responseTimings.Connection{LocalAddr: localAddr},
+	}
+}
+
+func TestBuildSourceLatencies(t *testing.T) {
+	assert := assert.New(t)
+	timings := []*responseTimings.Timing{
+		sourceTiming("127.0.0.1:50001", 10*time.Millisecond),
+		sourceTiming("127.0.0.1:50002", 20*time.Millisecond),
+		sourceTiming("127.0.0.2:50003", 30*time.Millisecond),
+		sourceTiming("", 40*time.Millisecond),
+		nil,
+	}
+
+	sources := BuildSourceLatencies(timings, time.Millisecond)
+
+	assert.Equal(map[string]int{"127.0.0.1": 2, "127.0.0.2": 1}, sources.Counts)
+	assert.Equal(20*time.Millisecond, sources.Percentiles["127.0.0.1"].Data[100])
+	assert.Equal(30*time.Millisecond, sources.Percentiles["127.0.0.2"].Data[50])
+	assert.Equal(`127.0.0.1: 2x  50th: 10ms  95th: 15ms  99th: 15ms  100th: 20ms
+127.0.0.2: 1x  50th: 30ms  95th: 30ms  99th: 30ms  100th: 30ms
+`, sources.String())
+}
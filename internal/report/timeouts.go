@@ -0,0 +1,35 @@
+package report
+
+import (
+	"fmt"
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+)
+
+type TimeoutBreakdown struct {
+	TotalCount   int
+	TimeoutCount int
+	Phases       map[string]int
+}
+
+func BuildTimeoutBreakdown(responses []*responseTimings.Response) (breakdown TimeoutBreakdown) {
+	breakdown = TimeoutBreakdown{Phases: make(map[string]int)}
+	for _, response := range responses {
+		if response == nil {
+			continue
+		}
+		breakdown.TotalCount++
+		if response.TimeoutPhase != "" {
+			breakdown.TimeoutCount++
+			breakdown.Phases[response.TimeoutPhase]++
+		}
+	}
+	return
+}
+
+func (t TimeoutBreakdown) String() string {
+	percentage := 0.0
+	if t.TotalCount > 0 {
+		percentage = float64(t.TimeoutCount) / float64(t.TotalCount) * 100
+	}
+	return fmt.Sprintf("Timed out: %d of %d (%.0f%%)\n", t.TimeoutCount, t.TotalCount, percentage) + bars(t.Phases, t.TotalCount)
+}
@@ -0,0 +1,39 @@
+package report
+
+import (
+	"github.com/JamesBalazs/lode/internal/responseTimings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuildTimeoutBreakdown(t *testing.T) {
+	responses := []*responseTimings.Response{
+		{StatusCode: 200},
+		{TimeoutPhase: responseTimings.PhaseDial},
+		{TimeoutPhase: responseTimings.PhaseResponseHeader},
+		{TimeoutPhase: responseTimings.PhaseResponseHeader},
+		nil,
+	}
+
+	breakdown := BuildTimeoutBreakdown(responses)
+
+	assert.Equal(t, TimeoutBreakdown{
+		TotalCount:   4,
+		TimeoutCount: 3,
+		Phases:       map[string]int{"dial": 1, "response header": 2},
+	}, breakdown)
+}
+
+func TestTimeoutBreakdown_String(t *testing.T) {
+	breakdown := TimeoutBreakdown{
+		TotalCount:   4,
+		TimeoutCount: 3,
+		Phases:       map[string]int{"dial": 1, "response header": 2},
+	}
+
+	assert.Equal(t, `Timed out: 3 of 4 (75%)
+dial:            =====>                1x
+response header: ==========>           2x
+`, breakdown.String())
+	assert.Equal(t, "Timed out: 0 of 0 (0%)\n", TimeoutBreakdown{}.String())
+}
@@ -0,0 +1,56 @@
+package responseTimings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"unicode/utf8"
+)
+
+type BodyCapture struct {
+	MaxBytes   int64
+	FailedOnly bool
+	Every      int
+	HashOnly   bool
+}
+
+// Measure records the size of the request and response bodies as they were sent and received,
+// before redaction can change them
+func (b BodyCapture) Measure(responseTiming ResponseTiming) {
+	responseTiming.Response.BodySize = int64(len(responseTiming.Response.Body))
+	if request := responseTiming.Request; request != nil {
+		request.BodySize = int64(len(request.Body))
+	}
+}
+
+// Apply records the hash of the request and response bodies, then trims or drops them according to
+// the capture policy. index is the 1-based position of the response in the run. Bodies should be
+// measured first, and redacted in full before they're hashed and trimmed, so the hash doesn't come from secrets.
+func (b BodyCapture) Apply(responseTiming ResponseTiming, index int) {
+	response, request := responseTiming.Response, responseTiming.Request
+	skipped := (b.FailedOnly && !response.Failed()) || (b.Every > 1 && index%b.Every != 0)
+	keep := !b.HashOnly && !skipped
+
+	response.Body, response.BodyHash, response.BodyTruncated = b.capture(response.Body, keep)
+	if request != nil {
+		request.Body, request.BodyHash, request.BodyTruncated = b.capture(request.Body, keep)
+	}
+}
+
+func (b BodyCapture) capture(body string, keep bool) (captured string, hash string, truncated bool) {
+	if len(body) > 0 {
+		sum := sha256.Sum256([]byte(body))
+		hash = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	if !keep {
+		return "", hash, false
+	} else if b.MaxBytes > 0 && int64(len(body)) > b.MaxBytes {
+		// trim on a rune boundary, so the stored body is still valid text
+		end := int(b.MaxBytes)
+		for end > 0 && !utf8.RuneStart(body[end]) {
+			end--
+		}
+		return body[:end], hash, true
+	}
+	return body, hash, false
+}
@@ -0,0 +1,119 @@
+package responseTimings
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"unicode/utf8"
+)
+
+const bodyHash = "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestBodyCapture_Apply(t *testing.T) {
+	assert := assert.New(t)
+	response := &Response{StatusCode: 200, Body: "hello"}
+
+	BodyCapture{}.Measure(ResponseTiming{Response: response})
+	BodyCapture{}.Apply(ResponseTiming{Response: response}, 1)
+
+	assert.Equal("hello", response.Body)
+	assert.Equal(int64(5), response.BodySize)
+	assert.Equal(bodyHash, response.BodyHash)
+	assert.False(response.BodyTruncated)
+}
+
+func TestBodyCapture_ApplyMaxBytes(t *testing.T) {
+	assert := assert.New(t)
+	response := &Response{StatusCode: 200, Body: "hello"}
+
+	BodyCapture{MaxBytes: 2}.Measure(ResponseTiming{Response: response})
+	BodyCapture{MaxBytes: 2}.Apply(ResponseTiming{Response: response}, 1)
+
+	assert.Equal("he", response.Body)
+	assert.Equal(int64(5), response.BodySize)
+	assert.Equal(bodyHash, response.BodyHash)
+	assert.True(response.BodyTruncated)
+}
+
+func TestBodyCapture_ApplyMaxBytesRuneBoundary(t *testing.T) {
+	assert := assert.New(t)
+	response := &Response{StatusCode: 200, Body: "héllo"}
+
+	BodyCapture{MaxBytes: 2}.Apply(ResponseTiming{Response: response}, 1)
+
+	assert.Equal("h", response.Body)
+	assert.True(utf8.ValidString(response.Body))
+	assert.True(response.BodyTruncated)
+}
+
+func TestBodyCapture_ApplyFailedOnly(t *testing.T) {
+	assert := assert.New(t)
+	success := &Response{StatusCode: 200, Body: "hello"}
+	failure := &Response{StatusCode: 503, Body: "hello"}
+	capture := BodyCapture{FailedOnly: true}
+
+	capture.Apply(ResponseTiming{Response: success}, 1)
+	capture.Apply(ResponseTiming{Response: failure}, 2)
+
+	assert.Equal("", success.Body)
+	assert.Equal(bodyHash, success.BodyHash)
+	assert.Equal("hello", failure.Body)
+}
+
+func TestBodyCapture_ApplyEvery(t *testing.T) {
+	assert := assert.New(t)
+	capture := BodyCapture{Every: 3}
+	var bodies []string
+
+	for i := 1; i <= 6; i++ {
+		response := &Response{StatusCode: 200, Body: "hello"}
+		capture.Apply(ResponseTiming{Response: response}, i)
+		bodies = append(bodies, response.Body)
+	}
+
+	assert.Equal([]string{"", "", "hello", "", "", "hello"}, bodies)
+}
+
+func TestBodyCapture_ApplyHashOnly(t *testing.T) {
+	assert := assert.New(t)
+	response := &Response{StatusCode: 503, Body: "hello"}
+
+	BodyCapture{HashOnly: true}.Measure(ResponseTiming{Response: response})
+	BodyCapture{HashOnly: true}.Apply(ResponseTiming{Response: response}, 1)
+
+	assert.Equal("", response.Body)
+	assert.Equal(int64(5), response.BodySize)
+	assert.Equal(bodyHash, response.BodyHash)
+}
+
+func TestBodyCapture_ApplyRequest(t *testing.T) {
+	assert := assert.New(t)
+	request := &Request{Body: "hello"}
+	response := &Response{StatusCode: 200, Body: "hello"}
+	capture := BodyCapture{MaxBytes: 2}
+
+	capture.Measure(ResponseTiming{Request: request, Response: response})
+	capture.Apply(ResponseTiming{Request: request, Response: response}, 1)
+
+	assert.Equal("he", request.Body)
+	assert.Equal(int64(5), request.BodySize)
+	assert.Equal(bodyHash, request.BodyHash)
+	assert.True(request.BodyTruncated)
+
+	request = &Request{Body: "hello"}
+	capture = BodyCapture{FailedOnly: true}
+
+	capture.Apply(ResponseTiming{Request: request, Response: response}, 1)
+
+	assert.Equal("", request.Body)
+	assert.Equal(bodyHash, request.BodyHash)
+}
+
+func TestBodyCapture_Measure(t *testing.T) {
+	request := &Request{Body: "hi"}
+	response := &Response{Body: "hello"}
+
+	BodyCapture{}.Measure(ResponseTiming{Request: request, Response: response})
+
+	assert.Equal(t, int64(2), request.BodySize)
+	assert.Equal(t, int64(5), response.BodySize)
+}
@@ -0,0 +1,94 @@
+package responseTimings
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"time"
+)
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+type Connection struct {
+	Reused         bool
+	WasIdle        bool
+	IdleTime       time.Duration
+	RemoteAddr     string
+	LocalAddr      string // source address the connection was made from
+	Proxy          string // proxy URL, with any password masked
+	ResolvedAddrs  []string
+	Protocol       string
+	TlsVersion     string
+	TlsCipherSuite string
+	TlsResumed     bool
+	resolution     time.Duration // set with Timing.SetResolution
+}
+
+func (c *Connection) setConnInfo(info httptrace.GotConnInfo) {
+	c.Reused = info.Reused
+	c.WasIdle = info.WasIdle
+	c.IdleTime = info.IdleTime
+	if info.Conn != nil {
+		c.RemoteAddr = info.Conn.RemoteAddr().String()
+		c.LocalAddr = info.Conn.LocalAddr().String()
+	}
+}
+
+func (c *Connection) setDnsInfo(info httptrace.DNSDoneInfo) {
+	c.ResolvedAddrs = nil
+	for _, addr := range info.Addrs {
+		c.ResolvedAddrs = append(c.ResolvedAddrs, addr.String())
+	}
+}
+
+func (c *Connection) SetTls(state tls.ConnectionState) {
+	if version, ok := tlsVersions[state.Version]; ok {
+		c.TlsVersion = version
+	} else if state.Version != 0 {
+		c.TlsVersion = fmt.Sprintf("0x%04x", state.Version)
+	}
+	if state.CipherSuite != 0 {
+		c.TlsCipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	}
+	c.TlsResumed = state.DidResume
+}
+
+func (c Connection) String() string {
+	reused := "new"
+	if c.Reused {
+		reused = fmt.Sprintf("reused (idle for %s)", truncate(c.IdleTime, c.resolution))
+	}
+	tlsDetails := "none"
+	if c.TlsVersion != "" {
+		tlsDetails = fmt.Sprintf("%s %s", c.TlsVersion, c.TlsCipherSuite)
+		if c.TlsResumed {
+			tlsDetails += " (resumed)"
+		}
+	}
+	local := ""
+	if c.LocalAddr != "" {
+		local = "\nLocal:         " + c.LocalAddr
+	}
+	proxy := ""
+	if c.Proxy != "" {
+		proxy = "\nProxy:         " + c.Proxy
+	}
+	return fmt.Sprintf(`Connection:    %s
+Remote:        %s%s%s
+Resolved:      %s
+Protocol:      %s
+TLS:           %s`,
+		reused,
+		c.RemoteAddr,
+		local,
+		proxy,
+		strings.Join(c.ResolvedAddrs, ", "),
+		c.Protocol,
+		tlsDetails)
+}
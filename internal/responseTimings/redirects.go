@@ -0,0 +1,32 @@
+package responseTimings
+
+import "time"
+
+// Redirect is a hop that was redirected from, before the final response
+type Redirect struct {
+	Url        string
+	StatusCode int
+	Timing     Timing
+}
+
+// Redirected moves the hop timed so far into Redirects, so the next hop is timed from scratch.
+func (t *Timing) Redirected(url string, statusCode int) {
+	hop := *t
+	hop.Redirects, hop.Done = nil, time.Now()
+	if len(t.Redirects) > 0 {
+		hop.Start = t.Redirects[len(t.Redirects)-1].Timing.Done
+	}
+	*t = Timing{
+		Start:     t.Start,
+		Redirects: append(t.Redirects, Redirect{Url: url, StatusCode: statusCode, Timing: hop}),
+	}
+	t.SetResolution(hop.resolution)
+}
+
+// RedirectDuration is the time spent on hops that were redirected from
+func (t Timing) RedirectDuration() (duration time.Duration) {
+	for _, redirect := range t.Redirects {
+		duration += redirect.Timing.TotalDuration()
+	}
+	return
+}
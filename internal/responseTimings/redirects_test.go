@@ -0,0 +1,56 @@
+package responseTimings
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTiming_Redirected(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Now().Add(-time.Second)
+	timing := Timing{
+		Start:      start,
+		GotConn:    start,
+		FirstByte:  start.Add(10 * time.Millisecond),
+		Connection: Connection{RemoteAddr: "127.0.0.1:80"},
+	}
+	timing.SetResolution(time.Microsecond)
+
+	timing.Redirected("http://lode.test/old", 301)
+	timing.GotConn = timing.Redirects[0].Timing.Done
+	timing.Redirected("http://lode.test/older", 302)
+
+	assert.Equal(start, timing.Start)
+	assert.True(timing.GotConn.IsZero())
+	assert.Equal(Connection{resolution: time.Microsecond}, timing.Connection)
+	assert.Equal(time.Microsecond, timing.resolution)
+	assert.Len(timing.Redirects, 2)
+	first, second := timing.Redirects[0], timing.Redirects[1]
+	assert.Equal("http://lode.test/old", first.Url)
+	assert.Equal(301, first.StatusCode)
+	assert.Equal(start, first.Timing.Start)
+	assert.Equal("127.0.0.1:80", first.Timing.Connection.RemoteAddr)
+	assert.False(first.Timing.Done.Before(first.Timing.FirstByte))
+	assert.Equal(302, second.StatusCode)
+	assert.Equal(first.Timing.Done, second.Timing.Start)
+	assert.Nil(second.Timing.Redirects)
+}
+
+func TestTiming_RedirectDuration(t *testing.T) {
+	assert := assert.New(t)
+	hop := func(duration time.Duration) Redirect {
+		return Redirect{Timing: Timing{GotConn: time.Unix(0, 0), Done: time.Unix(0, 0).Add(duration)}}
+	}
+	timing := Timing{
+		GotConn:   time.Unix(1, 0),
+		Done:      time.Unix(1, 0).Add(100 * time.Millisecond),
+		Redirects: []Redirect{hop(20 * time.Millisecond), hop(30 * time.Millisecond)},
+	}
+
+	assert.Equal(50*time.Millisecond, timing.RedirectDuration())
+	assert.Equal(150*time.Millisecond, timing.TotalDuration())
+	assert.True(strings.HasPrefix(timing.String(), "                Redirects:         50ms (2 hops)\n<=>             DNS Lookup:"))
+	assert.Equal(time.Duration(0), Timing{}.RedirectDuration())
+}
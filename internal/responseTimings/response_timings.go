@@ -9,12 +9,33 @@ import (
 )
 
 type ResponseTiming struct {
+	Request  *Request
 	Response *Response
 	Timing   *Timing
+	Warmup   bool `json:",omitempty" yaml:",omitempty"` // made during the warm-up period, so left out of statistics
 }
 
 type ResponseTimings []ResponseTiming
 
+// Measured leaves out the responses made during the warm-up period
+func (r ResponseTimings) Measured() (measured ResponseTimings) {
+	for _, responseTiming := range r {
+		if !responseTiming.Warmup {
+			measured = append(measured, responseTiming)
+		}
+	}
+	return
+}
+
+// SetResolution sets what every timing's durations are truncated to
+func (r ResponseTimings) SetResolution(resolution time.Duration) {
+	for _, responseTiming := range r {
+		if responseTiming.Timing != nil {
+			responseTiming.Timing.SetResolution(resolution)
+		}
+	}
+}
+
 func (r ResponseTimings) Responses() (responses []*Response) {
 	for _, responseTiming := range r {
 		responses = append(responses, responseTiming.Response)
@@ -42,12 +63,41 @@ func (r ResponseTimings) GetLongestDuration() (duration time.Duration) {
 	return
 }
 
+type Request struct {
+	Method        string
+	Url           string // after following any redirects
+	Header        Header
+	Body          string
+	BodySize      int64
+	BodyHash      string
+	BodyTruncated bool
+	WorkerId      int
+	Attempt       int // lode doesn't retry requests yet, so this is always 1
+}
+
 type Response struct {
 	Status        string // e.g. "200 OK"
 	StatusCode    int    // e.g. 200
 	ContentLength int64
 	Header        Header
 	Body          string
+	BodySize      int64
+	BodyHash      string
+	BodyTruncated bool
+	WireSize      int64 // body bytes received, before any decompression
+	DecodedSize   int64 // body bytes after decompression
+	Error         string
+	TimeoutPhase  string // dial, proxy tunnel, tls handshake, response header or body, if the request timed out
+}
+
+// FailedStatus matches the status codes that give lode a non-zero exit code, including 0 when no response was received
+func FailedStatus(statusCode int) bool {
+	return statusCode < 100 || statusCode >= 400
+}
+
+// Failed is true for a failed status code, or when the response couldn't be read in full
+func (r Response) Failed() bool {
+	return FailedStatus(r.StatusCode) || r.Error != ""
 }
 
 type Header struct {
@@ -31,6 +31,14 @@ func TestResponseTimings_Timings(t *testing.T) {
 	assert.Equal(t, []*Timing{timing, timing}, responseTimings.Timings())
 }
 
+func TestResponseTimings_Measured(t *testing.T) {
+	warmup := ResponseTiming{Response: &Response{StatusCode: 503}, Warmup: true}
+	measured := ResponseTiming{Response: &Response{StatusCode: 200}}
+	responseTimings := ResponseTimings{warmup, measured, warmup, measured}
+
+	assert.Equal(t, ResponseTimings{measured, measured}, responseTimings.Measured())
+}
+
 func TestResponseTimings_GetLongestDuration(t *testing.T) {
 	response := &Response{StatusCode: 200}
 	responseTimings := ResponseTimings{
@@ -63,3 +71,22 @@ func TestHeaderString(t *testing.T) {
 		"\033[36mSet-Cookie\033[0m: abc=\"def\"\n",
 		header.String())
 }
+
+func TestFailedStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(FailedStatus(0))
+	assert.False(FailedStatus(100))
+	assert.False(FailedStatus(200))
+	assert.False(FailedStatus(399))
+	assert.True(FailedStatus(400))
+	assert.True(FailedStatus(503))
+}
+
+func TestResponse_Failed(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(Response{StatusCode: 200}.Failed())
+	assert.True(Response{StatusCode: 503}.Failed())
+	assert.True(Response{StatusCode: 200, Error: "unexpected EOF"}.Failed())
+}
@@ -0,0 +1,25 @@
+package responseTimings
+
+const (
+	PhaseDial           = "dial"
+	PhaseProxyTunnel    = "proxy tunnel"
+	PhaseTlsHandshake   = "tls handshake"
+	PhaseResponseHeader = "response header"
+	PhaseBody           = "body"
+)
+
+// TimeoutPhase works out which phase a timed out request was in from the trace events it got to.
+// DNS lookups count towards the dial phase, as they are covered by the dial timeout.
+func (t Timing) TimeoutPhase() string {
+	if !t.FirstByte.IsZero() {
+		return PhaseBody
+	} else if !t.GotConn.IsZero() {
+		return PhaseResponseHeader
+	} else if !t.TlsStart.IsZero() {
+		// TlsDone is also set when the handshake fails, so it can't be used here
+		return PhaseTlsHandshake
+	} else if !t.TunnelStart.IsZero() {
+		return PhaseProxyTunnel
+	}
+	return PhaseDial
+}
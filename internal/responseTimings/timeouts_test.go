@@ -0,0 +1,20 @@
+package responseTimings
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTiming_TimeoutPhase(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+
+	assert.Equal(PhaseDial, Timing{}.TimeoutPhase())
+	assert.Equal(PhaseDial, Timing{DnsStart: now, ConnectStart: now}.TimeoutPhase())
+	assert.Equal(PhaseProxyTunnel, Timing{ConnectDone: now, TunnelStart: now}.TimeoutPhase())
+	assert.Equal(PhaseTlsHandshake, Timing{ConnectDone: now, TlsStart: now}.TimeoutPhase())
+	assert.Equal(PhaseTlsHandshake, Timing{ConnectDone: now, TlsStart: now, TlsDone: now}.TimeoutPhase())
+	assert.Equal(PhaseResponseHeader, Timing{TlsStart: now, TlsDone: now, GotConn: now}.TimeoutPhase())
+	assert.Equal(PhaseBody, Timing{GotConn: now, FirstByte: now}.TimeoutPhase())
+}
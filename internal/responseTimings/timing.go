@@ -1,24 +1,52 @@
 package responseTimings
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net/http/httptrace"
 	"time"
 )
 
-const TimingResolution = 1 * time.Millisecond
+// DefaultResolution is what durations are truncated to when no resolution was set
+const DefaultResolution = time.Millisecond
 
 type Timing struct {
+	Start        time.Time
 	DnsStart     time.Time
 	DnsDone      time.Time
 	ConnectStart time.Time
 	ConnectDone  time.Time
+	TunnelStart  time.Time // proxy CONNECT request sent, or SOCKS handshake started
+	TunnelDone   time.Time
 	TlsStart     time.Time
 	TlsDone      time.Time
 	GotConn      time.Time
 	FirstByte    time.Time
 	Done         time.Time
+	Connection   Connection
+	Redirects    []Redirect    `json:",omitempty" yaml:",omitempty"` // earlier hops, when redirects were followed
+	resolution   time.Duration // not recorded, as it comes from the run's params
+}
+
+// SetResolution sets what durations are truncated to, for the timing, its connection and its earlier hops
+func (t *Timing) SetResolution(resolution time.Duration) {
+	t.resolution = resolution
+	t.Connection.resolution = resolution
+	for i := range t.Redirects {
+		t.Redirects[i].Timing.SetResolution(resolution)
+	}
+}
+
+func (t Timing) truncate(duration time.Duration) time.Duration {
+	return truncate(duration, t.resolution)
+}
+
+func truncate(duration time.Duration, resolution time.Duration) time.Duration {
+	if resolution <= 0 {
+		resolution = DefaultResolution
+	}
+	return duration.Truncate(resolution)
 }
 
 func (t Timing) StartTime() time.Time {
@@ -34,7 +62,7 @@ func (t Timing) StartTime() time.Time {
 }
 
 func (t Timing) DnsLookupDuration() time.Duration {
-	return t.DnsDone.Sub(t.DnsStart).Truncate(TimingResolution)
+	return t.truncate(t.DnsDone.Sub(t.DnsStart))
 }
 
 func (t Timing) TcpConnectDuration() (result time.Duration) {
@@ -44,19 +72,23 @@ func (t Timing) TcpConnectDuration() (result time.Duration) {
 		result = t.ConnectDone.Sub(t.DnsDone)
 	}
 
-	return result.Truncate(TimingResolution)
+	return t.truncate(result)
+}
+
+func (t Timing) ProxyTunnelDuration() time.Duration {
+	return t.truncate(t.TunnelDone.Sub(t.TunnelStart))
 }
 
 func (t Timing) TlsHandshakeDuration() time.Duration {
-	return t.TlsDone.Sub(t.TlsStart).Truncate(TimingResolution)
+	return t.truncate(t.TlsDone.Sub(t.TlsStart))
 }
 
 func (t Timing) ServerDuration() time.Duration {
-	return t.FirstByte.Sub(t.GotConn).Truncate(TimingResolution)
+	return t.truncate(t.FirstByte.Sub(t.GotConn))
 }
 
 func (t Timing) ResponseTransferDuration() time.Duration {
-	return t.Done.Sub(t.FirstByte).Truncate(TimingResolution)
+	return t.truncate(t.Done.Sub(t.FirstByte))
 }
 
 func (t Timing) TotalDuration() time.Duration {
@@ -74,18 +106,28 @@ func (t Timing) TotalDuration() time.Duration {
 		return time.Duration(0)
 	}
 
-	return t.Done.Sub(start).Truncate(TimingResolution)
+	return t.truncate(t.Done.Sub(start) + t.RedirectDuration())
 }
 
 func (t Timing) String() string {
-	return fmt.Sprintf(`<=>             DNS Lookup:        %s
-   <=>          TCP Connection:    %s
+	tunnel := ""
+	if !t.TunnelStart.IsZero() {
+		tunnel = fmt.Sprintf("\n   <=>          Proxy Tunnel:      %s", t.ProxyTunnelDuration())
+	}
+	redirects := ""
+	if len(t.Redirects) > 0 {
+		redirects = fmt.Sprintf("                Redirects:         %s (%d hops)\n", t.RedirectDuration(), len(t.Redirects))
+	}
+	return fmt.Sprintf(`%s<=>             DNS Lookup:        %s
+   <=>          TCP Connection:    %s%s
       <=>       TLS Handshake:     %s
          <=>    Server:            %s
             <=> Response Transfer: %s
 <=============> Total:             %s`,
+		redirects,
 		t.DnsLookupDuration(),
 		t.TcpConnectDuration(),
+		tunnel,
 		t.TlsHandshakeDuration(),
 		t.ServerDuration(),
 		t.ResponseTransferDuration(),
@@ -95,12 +137,33 @@ func (t Timing) String() string {
 func NewTrace(timing *Timing) *httptrace.ClientTrace {
 	return &httptrace.ClientTrace{
 		DNSStart:             func(_ httptrace.DNSStartInfo) { timing.DnsStart = time.Now() },
-		DNSDone:              func(_ httptrace.DNSDoneInfo) { timing.DnsDone = time.Now() },
+		DNSDone:              func(info httptrace.DNSDoneInfo) { timing.DnsDone = time.Now(); timing.Connection.setDnsInfo(info) },
 		ConnectStart:         func(_, _ string) { timing.ConnectStart = time.Now() },
 		ConnectDone:          func(_, _ string, _ error) { timing.ConnectDone = time.Now() },
-		GotConn:              func(_ httptrace.GotConnInfo) { timing.GotConn = time.Now() },
+		GotConn:              func(info httptrace.GotConnInfo) { timing.GotConn = time.Now(); timing.Connection.setConnInfo(info) },
 		GotFirstResponseByte: func() { timing.FirstByte = time.Now() },
-		TLSHandshakeStart:    func() { timing.TlsStart = time.Now() },
-		TLSHandshakeDone:     func(_ tls.ConnectionState, _ error) { timing.TlsDone = time.Now() },
+		TLSHandshakeStart:    func() { timing.startTls() },
+		TLSHandshakeDone:     func(state tls.ConnectionState, _ error) { timing.TlsDone = time.Now(); timing.Connection.SetTls(state) },
+	}
+}
+
+// startTls also ends the proxy tunnel, as the origin handshake begins as soon as a CONNECT succeeds
+func (t *Timing) startTls() {
+	t.TlsStart = time.Now()
+	if !t.TunnelStart.IsZero() && t.TunnelDone.IsZero() {
+		t.TunnelDone = t.TlsStart
 	}
 }
+
+type timingContextKey struct{}
+
+// WithTiming attaches timing to ctx, so dialers can record events that httptrace has no hooks for.
+func WithTiming(ctx context.Context, timing *Timing) context.Context {
+	return context.WithValue(ctx, timingContextKey{}, timing)
+}
+
+// TimingFromContext returns the timing attached by WithTiming, or nil if there is none.
+func TimingFromContext(ctx context.Context) *Timing {
+	timing, _ := ctx.Value(timingContextKey{}).(*Timing)
+	return timing
+}
@@ -64,6 +64,23 @@ func TestTiming_TotalDuration(t *testing.T) {
 	assert.Equal(t, time.Duration(0), noTimingData.TotalDuration())
 }
 
+func TestTiming_SetResolution(t *testing.T) {
+	assert := assert.New(t)
+	timing := Timing{
+		GotConn:    time.Unix(0, 0),
+		Done:       time.Unix(0, 1_234_567),
+		Connection: Connection{Reused: true, IdleTime: 1_234_567},
+		Redirects:  []Redirect{{Timing: Timing{GotConn: time.Unix(0, 0), Done: time.Unix(0, 1_500)}}},
+	}
+	assert.Equal(time.Millisecond, timing.TotalDuration())
+
+	timing.SetResolution(time.Microsecond)
+
+	assert.Equal(1_235*time.Microsecond, timing.TotalDuration())
+	assert.Equal(time.Microsecond, timing.RedirectDuration())
+	assert.Contains(timing.Connection.String(), "reused (idle for 1.234ms)")
+}
+
 func TestTiming_String(t *testing.T) {
 	assert.Equal(t, `<=>             DNS Lookup:        2ms
    <=>          TCP Connection:    10ms
@@ -71,6 +88,22 @@ func TestTiming_String(t *testing.T) {
          <=>    Server:            14ms
             <=> Response Transfer: 16ms
 <=============> Total:             62ms`, timing.String())
+
+	proxied := timing
+	proxied.TunnelStart, proxied.TunnelDone = time.Unix(0, 14_000_000), time.Unix(0, 20_000_000)
+	assert.Contains(t, proxied.String(), `   <=>          TCP Connection:    10ms
+   <=>          Proxy Tunnel:      6ms
+      <=>       TLS Handshake:     10ms`)
+}
+
+func TestTiming_StartTlsEndsTunnel(t *testing.T) {
+	direct := Timing{}
+	direct.startTls()
+	assert.True(t, direct.TunnelDone.IsZero())
+
+	proxied := Timing{TunnelStart: time.Now()}
+	proxied.startTls()
+	assert.Equal(t, proxied.TlsStart, proxied.TunnelDone)
 }
 
 func TestNewTrace(t *testing.T) {